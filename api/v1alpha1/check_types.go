@@ -64,6 +64,199 @@ type CheckSpec struct {
 	// +kubebuilder:validation:MinItems=1
 	// +kubebuilder:validation:MaxItems=100
 	Channels []string `json:"channels,omitempty"`
+
+	// A Secret or ConfigMap to publish the ping URLs of the check to.
+	// +optional
+	PingURLTarget *PingURLTarget `json:"pingURLTarget,omitempty"`
+
+	// A Secret, in the namespace of the check, holding the healthchecks.io API key used to manage the check.
+	// Defaults to the API key of the operator.
+	// +optional
+	APIKeySecretRef *SecretKeyReference `json:"apiKeySecretRef,omitempty"`
+
+	// The HealthchecksProject or ClusterHealthchecksProject used to manage the check.
+	// Defaults to the settings of the operator.
+	// +optional
+	ProjectRef *ProjectReference `json:"projectRef,omitempty"`
+
+	// What happens to the check in healthchecks.io when the Check is deleted, defaults to the deletion policy of the operator.
+	// Delete removes the check, Retain keeps the check along with its ping history and detaches it from the operator.
+	// +optional
+	// +kubebuilder:validation:Enum=Delete;Retain
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+
+	// The UUID of an existing check in healthchecks.io to adopt instead of creating a new check.
+	// The adopted check is updated to match the spec, keeping its ping history and ping URL.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`
+	AdoptID string `json:"adoptID,omitempty"`
+
+	// The Jobs, in the namespace of the check, the operator sends pings for.
+	// A start ping is sent when a Job starts, a success ping when it completes and a fail ping when it fails.
+	// +optional
+	JobSelector *JobSelector `json:"jobSelector,omitempty"`
+
+	// The Jobs, in the namespace of the check, whose logs are sent with a fail ping when they fail.
+	// The termination message of the failed container is sent, or the tail of its logs when it has none.
+	// +optional
+	FailureLogsFrom *JobSelector `json:"failureLogsFrom,omitempty"`
+
+	// A probe the operator runs every timeout period, the check is pinged when the probe succeeds.
+	// Requires a timeout to be set.
+	// +optional
+	Probe *Probe `json:"probe,omitempty"`
+
+	// A rule on the health of an object, in the namespace of the check, the operator evaluates every minute.
+	// The check is pinged while the rule holds and a fail ping is sent when it stops holding.
+	// +optional
+	ResourceProbe *ResourceProbe `json:"resourceProbe,omitempty"`
+}
+
+// ResourceProbe describes a rule on the health of an object, exactly one of condition or replicasReady must be set
+type ResourceProbe struct {
+	// The API version of the object, e.g. apps/v1.
+	// +kubebuilder:validation:MinLength=1
+	APIVersion string `json:"apiVersion"`
+
+	// The kind of the object, e.g. Deployment.
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// The name of the object.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Holds when the object has a condition of the type with the status.
+	// +optional
+	Condition *ResourceCondition `json:"condition,omitempty"`
+
+	// Holds when status.readyReplicas of the object equals spec.replicas.
+	// +optional
+	ReplicasReady bool `json:"replicasReady,omitempty"`
+}
+
+// ResourceCondition matches a condition in the status of an object
+type ResourceCondition struct {
+	// The type of the condition, e.g. Available.
+	// +kubebuilder:validation:MinLength=1
+	Type string `json:"type"`
+
+	// The expected status of the condition, defaults to True.
+	// +optional
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status string `json:"status,omitempty"`
+}
+
+// Probe describes a synthetic probe of a target, exactly one of httpGet, tcpSocket or dns must be set
+type Probe struct {
+	// Performs an HTTP GET request against a URL.
+	// +optional
+	HTTPGet *HTTPGetProbe `json:"httpGet,omitempty"`
+
+	// Opens a TCP connection to an address.
+	// +optional
+	TCPSocket *TCPSocketProbe `json:"tcpSocket,omitempty"`
+
+	// Resolves a host name.
+	// +optional
+	DNS *DNSProbe `json:"dns,omitempty"`
+
+	// A number of seconds after which the probe times out, defaults to 10.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=60
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// HTTPGetProbe succeeds when a GET request returns the expected status and body
+type HTTPGetProbe struct {
+	// The URL to request, e.g. http://my-service.my-namespace.svc:8080/healthz.
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// The expected status code of the response, defaults to any 2xx status code.
+	// +optional
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	ExpectedStatus *int32 `json:"expectedStatus,omitempty"`
+
+	// A regular expression the body of the response must match.
+	// +optional
+	BodyMatch string `json:"bodyMatch,omitempty"`
+}
+
+// TCPSocketProbe succeeds when a TCP connection can be opened
+type TCPSocketProbe struct {
+	// The address to connect to in the format host:port, e.g. my-service.my-namespace.svc:5432.
+	// +kubebuilder:validation:MinLength=1
+	Address string `json:"address"`
+}
+
+// DNSProbe succeeds when a host name resolves to at least one address
+type DNSProbe struct {
+	// The host name to resolve, e.g. my-service.my-namespace.svc.cluster.local.
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+}
+
+// JobSelector selects the Jobs of a CronJob or the Jobs matching a label selector
+type JobSelector struct {
+	// The name of a CronJob whose Jobs are selected.
+	// +optional
+	CronJobName string `json:"cronJobName,omitempty"`
+
+	// A label selector for the Jobs to select.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// Deletion policies of a Check
+const (
+	// DeletionPolicyDelete deletes the check in healthchecks.io when the Check is deleted
+	DeletionPolicyDelete = "Delete"
+
+	// DeletionPolicyRetain keeps the check in healthchecks.io when the Check is deleted
+	DeletionPolicyRetain = "Retain"
+)
+
+// ProjectReference references a HealthchecksProject, in the namespace of the check, or a ClusterHealthchecksProject
+type ProjectReference struct {
+	// The kind of project, defaults to HealthchecksProject.
+	// +optional
+	// +kubebuilder:validation:Enum=HealthchecksProject;ClusterHealthchecksProject
+	Kind string `json:"kind,omitempty"`
+
+	// The name of the project.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// SecretKeyReference references a key of a Secret
+type SecretKeyReference struct {
+	// The name of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// The key of the Secret to select.
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+}
+
+// PingURLTarget references a Secret or ConfigMap, in the namespace of the check, that the ping URLs are written to
+type PingURLTarget struct {
+	// The kind of object to write the ping URLs to.
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+
+	// The name of the Secret or ConfigMap.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// The key the ping URL is written to, defaults to HC_PING_URL.
+	// The start, fail and log URLs are written to the same key suffixed with _START, _FAIL and _LOG.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z0-9]+$`
+	Key string `json:"key,omitempty"`
 }
 
 // CheckStatus defines the observed state of Check
@@ -95,9 +288,23 @@ type CheckStatus struct {
 	// +optional
 	PingURL string `json:"pingURL,omitempty"`
 
+	// When was the last time the probe of the check was run.
+	// +optional
+	LastProbe *metav1.Time `json:"lastProbe,omitempty"`
+
+	// A hash of the check last written to healthchecks.io, the check is only written again when it changes.
+	// +optional
+	AppliedSpecHash string `json:"appliedSpecHash,omitempty"`
+
 	// The last seen generation of the resource
 	// +optional
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The latest available observations of the check's state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // +kubebuilder:object:root=true
@@ -106,6 +313,7 @@ type CheckStatus struct {
 // +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
 // +kubebuilder:printcolumn:name="Timezone",type=string,JSONPath=`.spec.timezone`
 // +kubebuilder:printcolumn:name="GracePeriod",type=integer,JSONPath=`.spec.gracePeriod`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 // +kubebuilder:printcolumn:name="Status",priority=1,type=string,JSONPath=`.status.status`
 // +kubebuilder:printcolumn:name="Pings",priority=1,type=integer,JSONPath=`.status.pings`
 // +kubebuilder:printcolumn:name="LastPing",priority=1,type=string,format="date-time",JSONPath=`.status.lastPing`
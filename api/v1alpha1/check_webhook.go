@@ -0,0 +1,228 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// cronParser parses the five field cron format, and the descriptors such as @daily, supported by healthchecks.io
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// UUIDPattern matches the lowercase UUIDs healthchecks.io identifies checks by
+var UUIDPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// SetupWebhookWithManager registers the Check webhooks with the manager
+func (r *Check) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-monitoring-healthchecks-io-v1alpha1-check,mutating=false,failurePolicy=fail,groups=monitoring.healthchecks.io,resources=checks,versions=v1alpha1,name=vcheck.monitoring.healthchecks.io
+
+var _ webhook.Validator = &Check{}
+
+// ValidateCreate implements webhook.Validator
+func (r *Check) ValidateCreate() error {
+	return r.validateCheck()
+}
+
+// ValidateUpdate implements webhook.Validator. A Check being deleted, or with an unchanged spec, is not
+// validated, so the finalizer of a Check created before the rules were enforced can still be removed.
+func (r *Check) ValidateUpdate(old runtime.Object) error {
+	if r.DeletionTimestamp != nil {
+		return nil
+	}
+	if o, ok := old.(*Check); ok && equality.Semantic.DeepEqual(r.Spec, o.Spec) {
+		return nil
+	}
+
+	return r.validateCheck()
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *Check) ValidateDelete() error {
+	return nil
+}
+
+func (r *Check) validateCheck() error {
+	allErrs := ValidateCheckSpec(r.Spec, field.NewPath("spec"))
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrs.NewInvalid(GroupVersion.WithKind("Check").GroupKind(), r.Name, allErrs)
+}
+
+// ValidateCheckSpec validates the spec of a Check at path, as done by the Check webhook
+func ValidateCheckSpec(spec CheckSpec, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if spec.Schedule != "" {
+		if strings.HasPrefix(spec.Schedule, "@every") {
+			// an interval is not a cron expression, healthchecks.io does not support it
+			allErrs = append(allErrs, field.Invalid(path.Child("schedule"), spec.Schedule, "must be a valid cron expression, use timeout for an interval"))
+		} else if _, err := cronParser.Parse(spec.Schedule); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("schedule"), spec.Schedule, "must be a valid cron expression: "+err.Error()))
+		}
+	}
+
+	if spec.Timezone != "" {
+		// "Local" is accepted by LoadLocation but refers to the operators own timezone
+		if _, err := time.LoadLocation(spec.Timezone); err != nil || spec.Timezone == "Local" {
+			allErrs = append(allErrs, field.Invalid(path.Child("timezone"), spec.Timezone, "must be a timezone from the IANA tz database"))
+		}
+	}
+
+	if spec.Timeout != nil && spec.Schedule != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("timeout"), "may not be set when a schedule is set"))
+	}
+
+	if spec.GracePeriod != nil && spec.Schedule == "" && spec.Timeout == nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("gracePeriod"), "may only be set in combination with a schedule or a timeout"))
+	}
+
+	allErrs = append(allErrs, validateChannels(spec.Channels, path.Child("channels"))...)
+
+	if spec.AdoptID != "" && !UUIDPattern.MatchString(spec.AdoptID) {
+		allErrs = append(allErrs, field.Invalid(path.Child("adoptID"), spec.AdoptID, "must be the UUID of a check in healthchecks.io"))
+	}
+
+	if spec.JobSelector != nil {
+		allErrs = append(allErrs, validateJobSelector(*spec.JobSelector, path.Child("jobSelector"))...)
+	}
+
+	if spec.FailureLogsFrom != nil {
+		allErrs = append(allErrs, validateJobSelector(*spec.FailureLogsFrom, path.Child("failureLogsFrom"))...)
+	}
+
+	if spec.Probe != nil {
+		allErrs = append(allErrs, validateProbe(*spec.Probe, path.Child("probe"))...)
+		if spec.Timeout == nil {
+			allErrs = append(allErrs, field.Required(path.Child("timeout"), "must be set when a probe is set"))
+		}
+	}
+
+	if spec.ResourceProbe != nil {
+		allErrs = append(allErrs, validateResourceProbe(*spec.ResourceProbe, path.Child("resourceProbe"))...)
+		if spec.Probe != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("resourceProbe"), "may not be set in combination with probe"))
+		}
+	}
+
+	if spec.APIKeySecretRef != nil && spec.ProjectRef != nil {
+		allErrs = append(allErrs, field.Forbidden(path.Child("apiKeySecretRef"), "may not be set in combination with projectRef"))
+	}
+
+	return allErrs
+}
+
+func validateJobSelector(selector JobSelector, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if (selector.CronJobName == "") == (selector.Selector == nil) {
+		allErrs = append(allErrs, field.Invalid(path, selector, "exactly one of cronJobName or selector must be set"))
+	}
+
+	if selector.Selector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(selector.Selector); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("selector"), selector.Selector, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+func validateProbe(probe Probe, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	set := 0
+	if probe.HTTPGet != nil {
+		set++
+		if u, err := url.Parse(probe.HTTPGet.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(path.Child("httpGet", "url"), probe.HTTPGet.URL, "must be an absolute http or https URL"))
+		}
+		if probe.HTTPGet.BodyMatch != "" {
+			if _, err := regexp.Compile(probe.HTTPGet.BodyMatch); err != nil {
+				allErrs = append(allErrs, field.Invalid(path.Child("httpGet", "bodyMatch"), probe.HTTPGet.BodyMatch, "must be a valid regular expression: "+err.Error()))
+			}
+		}
+	}
+	if probe.TCPSocket != nil {
+		set++
+		if _, _, err := net.SplitHostPort(probe.TCPSocket.Address); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("tcpSocket", "address"), probe.TCPSocket.Address, "must be in the format host:port"))
+		}
+	}
+	if probe.DNS != nil {
+		set++
+	}
+
+	if set != 1 {
+		allErrs = append(allErrs, field.Invalid(path, probe, "exactly one of httpGet, tcpSocket or dns must be set"))
+	}
+
+	return allErrs
+}
+
+func validateResourceProbe(probe ResourceProbe, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if _, err := schema.ParseGroupVersion(probe.APIVersion); err != nil {
+		allErrs = append(allErrs, field.Invalid(path.Child("apiVersion"), probe.APIVersion, err.Error()))
+	}
+
+	if (probe.Condition == nil) == !probe.ReplicasReady {
+		allErrs = append(allErrs, field.Invalid(path, probe, "exactly one of condition or replicasReady must be set"))
+	}
+
+	return allErrs
+}
+
+func validateChannels(channels []string, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, channel := range channels {
+		if channel == "*" {
+			if len(channels) > 1 {
+				allErrs = append(allErrs, field.Invalid(path.Index(i), channel, `"*" may not be combined with other channels`))
+			}
+			continue
+		}
+
+		p := strings.Split(channel, "/")
+		if len(p) > 2 || strings.TrimSpace(p[0]) == "" || strings.ContainsAny(p[0], " \t") || (len(p) == 2 && strings.TrimSpace(p[1]) == "") {
+			allErrs = append(allErrs, field.Invalid(path.Index(i), channel, `must be "*" or in the format "kind" or "kind/name"`))
+		}
+	}
+
+	return allErrs
+}
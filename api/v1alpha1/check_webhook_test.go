@@ -0,0 +1,157 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestCheckWebhook_ValidateSchedule(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(newCheck(CheckSpec{Schedule: "*/10 * * * *"}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "0 8 * * 1-5"}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "* * * *"}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "0 0 0 * * *"}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "61 * * * *"}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "@daily"}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "@hourly"}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "@every 5m"}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "@fortnightly"}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateTimezone(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(newCheck(CheckSpec{Schedule: "* * * * *", Timezone: "UTC"}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "* * * * *", Timezone: "Europe/Stockholm"}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "* * * * *", Timezone: "Europe/Foo"}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "* * * * *", Timezone: "Local"}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateTimeoutAndGracePeriod(t *testing.T) {
+	g := NewGomegaWithT(t)
+	period := int32(3600)
+
+	g.Expect(newCheck(CheckSpec{Timeout: &period}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, GracePeriod: &period}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "* * * * *", GracePeriod: &period}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Schedule: "* * * * *", Timeout: &period}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{GracePeriod: &period}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateChannels(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(newCheck(CheckSpec{Channels: []string{"*"}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{"email"}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{"email/Email Me", "webhook"}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{"*", "email"}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{""}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{"/name"}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{"email/"}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{"email/foo/bar"}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Channels: []string{"e mail"}}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateAdoptID(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(newCheck(CheckSpec{AdoptID: "e71024f4-8537-4dd2-b742-ebe5a1685776"}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{AdoptID: "E71024F4-8537-4DD2-B742-EBE5A1685776"}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{AdoptID: "e71024f4"}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateJobSelector(t *testing.T) {
+	g := NewGomegaWithT(t)
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}}
+	invalid := &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Foo"}}}
+
+	g.Expect(newCheck(CheckSpec{JobSelector: &JobSelector{CronJobName: "backup"}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{JobSelector: &JobSelector{Selector: selector}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{JobSelector: &JobSelector{}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{JobSelector: &JobSelector{CronJobName: "backup", Selector: selector}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{JobSelector: &JobSelector{Selector: invalid}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{FailureLogsFrom: &JobSelector{CronJobName: "backup"}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{FailureLogsFrom: &JobSelector{}}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateProbe(t *testing.T) {
+	g := NewGomegaWithT(t)
+	period := int32(300)
+	httpGet := &HTTPGetProbe{URL: "http://api.default.svc:8080/healthz", BodyMatch: "^ok$"}
+	tcpSocket := &TCPSocketProbe{Address: "postgres.default.svc:5432"}
+
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{HTTPGet: httpGet}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{TCPSocket: tcpSocket}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{DNS: &DNSProbe{Host: "api.default.svc"}}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{Probe: &Probe{HTTPGet: httpGet}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{HTTPGet: httpGet, TCPSocket: tcpSocket}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{HTTPGet: &HTTPGetProbe{URL: "api.default.svc/healthz"}}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{HTTPGet: &HTTPGetProbe{URL: "http://api", BodyMatch: "("}}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{Timeout: &period, Probe: &Probe{TCPSocket: &TCPSocketProbe{Address: "postgres"}}}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateResourceProbe(t *testing.T) {
+	g := NewGomegaWithT(t)
+	period := int32(300)
+	available := &ResourceCondition{Type: "Available"}
+
+	g.Expect(newCheck(CheckSpec{ResourceProbe: &ResourceProbe{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Condition: available}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{ResourceProbe: &ResourceProbe{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "db", ReplicasReady: true}}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{ResourceProbe: &ResourceProbe{APIVersion: "apps/v1", Kind: "Deployment", Name: "api"}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{ResourceProbe: &ResourceProbe{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Condition: available, ReplicasReady: true}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{ResourceProbe: &ResourceProbe{APIVersion: "apps/v1/beta", Kind: "Deployment", Name: "api", Condition: available}}).ValidateCreate()).ToNot(Succeed())
+	g.Expect(newCheck(CheckSpec{
+		Timeout:       &period,
+		Probe:         &Probe{DNS: &DNSProbe{Host: "api.default.svc"}},
+		ResourceProbe: &ResourceProbe{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", Condition: available},
+	}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateProjectRef(t *testing.T) {
+	g := NewGomegaWithT(t)
+	apiKey := &SecretKeyReference{Name: "secret", Key: "key"}
+	project := &ProjectReference{Name: "project"}
+
+	g.Expect(newCheck(CheckSpec{APIKeySecretRef: apiKey}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{ProjectRef: project}).ValidateCreate()).To(Succeed())
+	g.Expect(newCheck(CheckSpec{APIKeySecretRef: apiKey, ProjectRef: project}).ValidateCreate()).ToNot(Succeed())
+}
+
+func TestCheckWebhook_ValidateUpdateAndDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	valid := newCheck(CheckSpec{Schedule: "* * * * *"})
+	invalid := newCheck(CheckSpec{Schedule: "foo"})
+
+	g.Expect(valid.ValidateUpdate(invalid)).To(Succeed())
+	g.Expect(invalid.ValidateUpdate(valid)).ToNot(Succeed())
+	g.Expect(invalid.ValidateDelete()).To(Succeed())
+}
+
+func TestCheckWebhook_ValidateUpdate_UnchangedOrDeleted(t *testing.T) {
+	g := NewGomegaWithT(t)
+	old := newCheck(CheckSpec{Schedule: "foo"})
+
+	finalized := old.DeepCopy()
+	finalized.Finalizers = []string{"check.finalizers.monitoring.healthchecks.io"}
+	g.Expect(finalized.ValidateUpdate(old)).To(Succeed())
+
+	deleted := newCheck(CheckSpec{Schedule: "bar"})
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	g.Expect(deleted.ValidateUpdate(old)).To(Succeed())
+
+	changed := newCheck(CheckSpec{Schedule: "bar"})
+	g.Expect(changed.ValidateUpdate(old)).ToNot(Succeed())
+}
+
+func newCheck(spec CheckSpec) *Check {
+	c := &Check{Spec: spec}
+	c.Name = "check"
+	c.Namespace = "default"
+	return c
+}
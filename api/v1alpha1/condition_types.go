@@ -0,0 +1,114 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types reported on a Check
+const (
+	// ConditionReady is true when the remote check is not down
+	ConditionReady = "Ready"
+
+	// ConditionSynced is true when the last create/update of the remote check succeeded
+	ConditionSynced = "Synced"
+
+	// ConditionChannelsResolved is true when every channel of the check matched a channel in healthchecks.io
+	ConditionChannelsResolved = "ChannelsResolved"
+
+	// ConditionProbeSucceeded is true when the last run of the probe, or resource probe, of the check succeeded
+	ConditionProbeSucceeded = "ProbeSucceeded"
+
+	// ConditionAPIUnavailable is true while the circuit of the healthchecks.io API of the check is open
+	ConditionAPIUnavailable = "ApiUnavailable"
+)
+
+// Condition describes one aspect of the current state of a resource
+type Condition struct {
+	// Type of condition in CamelCase.
+	// +kubebuilder:validation:MinLength=1
+	Type string `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown.
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status metav1.ConditionStatus `json:"status"`
+
+	// The generation of the resource the condition was set based upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// A programmatic identifier indicating the reason for the condition's last transition, in CamelCase.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// FindCondition returns the condition of the given type, or nil if it is not present
+func FindCondition(conditions []Condition, conditionType string) *Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of the same type in conditions.
+// LastTransitionTime is only changed when the status of the condition changes.
+func SetCondition(conditions *[]Condition, condition Condition) {
+	existing := FindCondition(*conditions, condition.Type)
+	if existing == nil {
+		*conditions = append(*conditions, condition)
+		return
+	}
+
+	if existing.Status != condition.Status {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = condition.LastTransitionTime
+	}
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	existing.ObservedGeneration = condition.ObservedGeneration
+}
+
+// RemoveCondition removes the condition of the given type from conditions
+func RemoveCondition(conditions *[]Condition, conditionType string) {
+	if FindCondition(*conditions, conditionType) == nil {
+		return
+	}
+
+	filtered := make([]Condition, 0, len(*conditions))
+	for _, c := range *conditions {
+		if c.Type != conditionType {
+			filtered = append(filtered, c)
+		}
+	}
+	*conditions = filtered
+}
+
+// IsConditionTrue returns true when the condition of the given type is present and has status True
+func IsConditionTrue(conditions []Condition, conditionType string) bool {
+	c := FindCondition(conditions, conditionType)
+	return c != nil && c.Status == metav1.ConditionTrue
+}
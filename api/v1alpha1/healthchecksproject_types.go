@@ -0,0 +1,156 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition types reported on a HealthchecksProject
+const (
+	// ConditionConnected is true when the healthchecks.io API of the project could be reached using its API key
+	ConditionConnected = "Connected"
+
+	// ConditionCheckLimitReached is true when the project holds as many checks as its check limit allows
+	ConditionCheckLimitReached = "CheckLimitReached"
+)
+
+// HealthchecksProjectSpec defines the desired state of HealthchecksProject
+type HealthchecksProjectSpec struct {
+	// The Secret holding the API key of the project.
+	APIKeySecretRef ProjectSecretKeyReference `json:"apiKeySecretRef"`
+
+	// The base URL of the healthchecks.io API, defaults to the API URL of the operator.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^https?://`
+	BaseURL string `json:"baseURL,omitempty"`
+
+	// PEM encoded CA certificates used to verify the healthchecks.io API, in addition to the system roots.
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// Skip verification of the certificate of the healthchecks.io API.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// Prefix used to create unique check names, defaults to the name prefix of the operator.
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+
+	// Tags added to every check of the project.
+	// +optional
+	// +kubebuilder:validation:MaxItems=100
+	DefaultTags []string `json:"defaultTags,omitempty"`
+
+	// Channels assigned to checks of the project that do not specify any channels.
+	// +optional
+	// +kubebuilder:validation:MaxItems=100
+	DefaultChannels []string `json:"defaultChannels,omitempty"`
+
+	// A limit on the number of checks of the project set by the user, e.g. the limit of its healthchecks.io plan.
+	// The healthchecks.io API does not expose plan limits, so it is not read from healthchecks.io.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	CheckLimit *int32 `json:"checkLimit,omitempty"`
+}
+
+// ProjectSecretKeyReference references a key of a Secret.
+type ProjectSecretKeyReference struct {
+	SecretKeyReference `json:",inline"`
+
+	// The namespace of the Secret. Required for a ClusterHealthchecksProject,
+	// a HealthchecksProject always reads the Secret from its own namespace.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// HealthchecksProjectStatus defines the observed state of HealthchecksProject
+type HealthchecksProjectStatus struct {
+	// The number of checks in the healthchecks.io project of the API key, including the checks not managed
+	// by the operator or not matching the name prefix, as they count towards the limits of its plan.
+	// +optional
+	Checks *int32 `json:"checks,omitempty"`
+
+	// When was the last time the project was successfully updated.
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+
+	// The last seen generation of the resource
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The latest available observations of the project's state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="BaseURL",type=string,JSONPath=`.spec.baseURL`
+// +kubebuilder:printcolumn:name="Connected",type=string,JSONPath=`.status.conditions[?(@.type=="Connected")].status`
+// +kubebuilder:printcolumn:name="Checks",type=integer,JSONPath=`.status.checks`,description="The number of checks in the healthchecks.io project"
+// +kubebuilder:printcolumn:name="UserCheckLimit",type=integer,JSONPath=`.spec.checkLimit`,description="The check limit set by the user"
+
+// HealthchecksProject is the Schema for the healthchecksprojects API
+type HealthchecksProject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HealthchecksProjectSpec   `json:"spec,omitempty"`
+	Status HealthchecksProjectStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// HealthchecksProjectList contains a list of HealthchecksProject
+type HealthchecksProjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HealthchecksProject `json:"items"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="BaseURL",type=string,JSONPath=`.spec.baseURL`
+// +kubebuilder:printcolumn:name="Connected",type=string,JSONPath=`.status.conditions[?(@.type=="Connected")].status`
+// +kubebuilder:printcolumn:name="Checks",type=integer,JSONPath=`.status.checks`,description="The number of checks in the healthchecks.io project"
+// +kubebuilder:printcolumn:name="UserCheckLimit",type=integer,JSONPath=`.spec.checkLimit`,description="The check limit set by the user"
+
+// ClusterHealthchecksProject is the Schema for the clusterhealthchecksprojects API
+type ClusterHealthchecksProject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HealthchecksProjectSpec   `json:"spec,omitempty"`
+	Status HealthchecksProjectStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ClusterHealthchecksProjectList contains a list of ClusterHealthchecksProject
+type ClusterHealthchecksProjectList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterHealthchecksProject `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&HealthchecksProject{}, &HealthchecksProjectList{})
+	SchemeBuilder.Register(&ClusterHealthchecksProject{}, &ClusterHealthchecksProjectList{})
+}
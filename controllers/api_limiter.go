@@ -0,0 +1,245 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultRetryAfter is how long requests are held back after a 429 response without a Retry-After header
+const defaultRetryAfter = 30 * time.Second
+
+// defaultMaxWait is how long a request waits for a token of the bucket before it fails
+const defaultMaxWait = 10 * time.Second
+
+// APILimiter throttles the requests of every healthchecks.io client with a shared token bucket. It holds
+// back the requests of an account while the API asks it to retry later, and opens a circuit for an API
+// failing repeatedly.
+type APILimiter struct {
+	Clock Clock
+
+	// FailureThreshold is the number of consecutive failures opening the circuit of an API
+	FailureThreshold int
+
+	// OpenDuration is how long requests fail fast once the circuit of an API is open
+	OpenDuration time.Duration
+
+	// MaxWait is how long a request waits for a token before it fails, so a backlog of requests does not stall the workers
+	MaxWait time.Duration
+
+	bucket *rate.Limiter
+
+	mu       sync.Mutex
+	hosts    map[string]*apiState
+	accounts map[string]time.Time
+}
+
+// RateLimitedError is returned for a request which waited longer than MaxWait for a token of the bucket.
+// It is throttled by the operator itself, the API was not called.
+type RateLimitedError struct {
+	Host string
+
+	// RetryAfter is how long it takes until the bucket has a token for the request
+	RetryAfter time.Duration
+}
+
+// Error returns the error message
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("too many requests queued for the api at %s, retrying after %s", e.Host, e.RetryAfter.Round(time.Second))
+}
+
+// apiState holds the failures of the API on a host
+type apiState struct {
+	failures   int
+	openUntil  time.Time
+	lastStatus string
+}
+
+// NewAPILimiter creates a new APILimiter allowing qps requests per second with bursts of burst requests
+func NewAPILimiter(qps float32, burst int, failureThreshold int, openDuration time.Duration) *APILimiter {
+	return &APILimiter{
+		Clock:            NewClock(),
+		FailureThreshold: failureThreshold,
+		OpenDuration:     openDuration,
+		MaxWait:          defaultMaxWait,
+		bucket:           rate.NewLimiter(rate.Limit(qps), burst),
+	}
+}
+
+// Client returns a copy of the http client sending its requests through the limiter
+func (l *APILimiter) Client(c *http.Client) *http.Client {
+	limited := *c
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	limited.Transport = &limitedTransport{limiter: l, next: transport}
+	return &limited
+}
+
+// Backoff returns how long to wait before calling the API at baseURL with the account again, and whether its circuit is open.
+// The circuit is shared by every account of the API, rate limits apply to a single account.
+func (l *APILimiter) Backoff(baseURL, account string) (time.Duration, bool) {
+	host := hostOf(baseURL)
+	now := l.Clock.Now().Time
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if state, ok := l.hosts[host]; ok {
+		if wait := state.openUntil.Sub(now); wait > 0 {
+			return wait, true
+		}
+	}
+	if wait := l.accounts[host+"|"+account].Sub(now); wait > 0 {
+		return wait, false
+	}
+	return 0, false
+}
+
+// before returns an error when requests of the account to the host are held back
+func (l *APILimiter) before(host, account string) error {
+	now := l.Clock.Now().Time
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.state(host)
+	if now.Before(state.openUntil) {
+		return fmt.Errorf("the api at %s is unavailable after %d consecutive failures (last: %s), retrying after %s", host, state.failures, state.lastStatus, state.openUntil.Format(time.RFC3339))
+	}
+	if retryAt := l.accounts[host+"|"+account]; now.Before(retryAt) {
+		return fmt.Errorf("rate limited by the api at %s, retrying after %s", host, retryAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// after records the outcome of a request of the account to the host
+func (l *APILimiter) after(host, account string, res *http.Response, err error) {
+	now := l.Clock.Now().Time
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.state(host)
+	switch {
+	case err != nil || res.StatusCode >= 500:
+		state.failures++
+		if err != nil {
+			state.lastStatus = err.Error()
+		} else {
+			state.lastStatus = res.Status
+		}
+		// the circuit opens again on the first failure after it was open
+		if l.FailureThreshold > 0 && state.failures >= l.FailureThreshold {
+			state.openUntil = now.Add(l.OpenDuration)
+		}
+	case res.StatusCode == http.StatusTooManyRequests:
+		if l.accounts == nil {
+			l.accounts = make(map[string]time.Time)
+		}
+		l.accounts[host+"|"+account] = now.Add(parseRetryAfter(res.Header.Get("Retry-After"), now))
+	default:
+		state.failures = 0
+		state.openUntil = time.Time{}
+		delete(l.accounts, host+"|"+account)
+	}
+}
+
+func (l *APILimiter) state(host string) *apiState {
+	if l.hosts == nil {
+		l.hosts = make(map[string]*apiState)
+	}
+
+	state, ok := l.hosts[host]
+	if !ok {
+		state = &apiState{}
+		l.hosts[host] = state
+	}
+	return state
+}
+
+// limitedTransport sends requests through an APILimiter
+type limitedTransport struct {
+	limiter *APILimiter
+	next    http.RoundTripper
+}
+
+// RoundTrip fails fast while the API is held back, and otherwise waits for a token before sending the request
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	account := AccountOf(req.Header.Get("X-Api-Key"))
+	if err := t.limiter.before(host, account); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.limiter.MaxWait)
+	defer cancel()
+	if err := t.limiter.bucket.Wait(ctx); err != nil {
+		if req.Context().Err() != nil {
+			return nil, req.Context().Err()
+		}
+		return nil, &RateLimitedError{Host: host, RetryAfter: t.limiter.queued()}
+	}
+
+	res, err := t.next.RoundTrip(req)
+	t.limiter.after(host, account, res, err)
+	return res, err
+}
+
+// queued returns how long a request waits for a token of the bucket, or MaxWait when it is unknown
+func (l *APILimiter) queued() time.Duration {
+	reservation := l.bucket.Reserve()
+	defer reservation.Cancel()
+
+	if delay := reservation.Delay(); delay > 0 && delay != rate.InfDuration {
+		return delay
+	}
+	return l.MaxWait
+}
+
+// parseRetryAfter parses a Retry-After header holding either seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return defaultRetryAfter
+}
+
+// AccountOf identifies the account of an API key without revealing the key
+func AccountOf(apiKey string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(apiKey)))
+}
+
+func hostOf(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL
+	}
+	return u.Host
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/gomega"
+)
+
+func newTestAPILimiter(now *metav1.Time, failureThreshold int) *APILimiter {
+	limiter := NewAPILimiter(1000, 1000, failureThreshold, time.Minute)
+	limiter.Clock = Clock{Source: func() *metav1.Time { return now }}
+	return limiter
+}
+
+func TestAPILimiter_RetryAfter(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests++
+		res.Header().Set("Retry-After", "20")
+		res.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	now := metav1.Now()
+	limiter := newTestAPILimiter(&now, 5)
+	client := limiter.Client(&http.Client{})
+	get := func(apiKey string) (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("X-Api-Key", apiKey)
+		return client.Do(req)
+	}
+
+	// Act & assert, a 429 response holds back requests for the duration of its Retry-After header
+	res, err := get("foo")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.StatusCode).To(Equal(http.StatusTooManyRequests))
+
+	wait, open := limiter.Backoff(server.URL, AccountOf("foo"))
+	g.Expect(wait).To(Equal(20 * time.Second))
+	g.Expect(open).To(BeFalse())
+
+	_, err = get("foo")
+	g.Expect(err).To(MatchError(ContainSubstring("rate limited")))
+	g.Expect(requests).To(Equal(1))
+
+	// Act & assert, other accounts are not held back
+	wait, _ = limiter.Backoff(server.URL, AccountOf("bar"))
+	g.Expect(wait).To(BeZero())
+	_, err = get("bar")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(requests).To(Equal(2))
+
+	// Act & assert, requests are sent again once Retry-After has passed
+	now = metav1.NewTime(now.Add(21 * time.Second))
+	wait, _ = limiter.Backoff(server.URL, AccountOf("foo"))
+	g.Expect(wait).To(BeZero())
+	_, err = get("foo")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(requests).To(Equal(3))
+}
+
+func TestAPILimiter_CircuitBreaker(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	status := http.StatusServiceUnavailable
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests++
+		res.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	now := metav1.Now()
+	limiter := newTestAPILimiter(&now, 3)
+	client := limiter.Client(&http.Client{})
+
+	// Act & assert, the circuit opens after the failure threshold is reached
+	for i := 0; i < 3; i++ {
+		_, open := limiter.Backoff(server.URL, AccountOf(""))
+		g.Expect(open).To(BeFalse())
+		_, err := client.Get(server.URL)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	wait, open := limiter.Backoff(server.URL, AccountOf(""))
+	g.Expect(open).To(BeTrue())
+	g.Expect(wait).To(Equal(time.Minute))
+
+	_, err := client.Get(server.URL)
+	g.Expect(err).To(MatchError(ContainSubstring("unavailable after 3 consecutive failures")))
+	g.Expect(requests).To(Equal(3))
+
+	// Act & assert, the circuit opens again on the first failure after it was open
+	now = metav1.NewTime(now.Add(time.Minute))
+	_, err = client.Get(server.URL)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, open = limiter.Backoff(server.URL, AccountOf(""))
+	g.Expect(open).To(BeTrue())
+
+	// Act & assert, the circuit closes once a request succeeds
+	now = metav1.NewTime(now.Add(time.Minute))
+	status = http.StatusOK
+	_, err = client.Get(server.URL)
+	g.Expect(err).ToNot(HaveOccurred())
+	wait, open = limiter.Backoff(server.URL, AccountOf(""))
+	g.Expect(open).To(BeFalse())
+	g.Expect(wait).To(BeZero())
+	g.Expect(requests).To(Equal(5))
+}
+
+func TestAPILimiter_MaxWait(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	limiter := NewAPILimiter(0.001, 1, 5, time.Minute)
+	limiter.MaxWait = 10 * time.Millisecond
+	client := limiter.Client(&http.Client{})
+
+	// Act & assert, requests fail instead of waiting longer than MaxWait for a token
+	_, err := client.Get(server.URL)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = client.Get(server.URL)
+	g.Expect(err).To(MatchError(ContainSubstring("too many requests queued")))
+
+	var limited *RateLimitedError
+	g.Expect(errors.As(err, &limited)).To(BeTrue())
+	g.Expect(limited.RetryAfter).To(BeNumerically(">", 10*time.Minute))
+
+	// Act & assert, local throttling is not held against the API
+	wait, open := limiter.Backoff(server.URL, AccountOf(""))
+	g.Expect(wait).To(BeZero())
+	g.Expect(open).To(BeFalse())
+}
+
+func TestAPILimiter_ParseRetryAfter(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := time.Date(2019, 11, 10, 12, 0, 0, 0, time.UTC)
+
+	g.Expect(parseRetryAfter("120", now)).To(Equal(2 * time.Minute))
+	g.Expect(parseRetryAfter("Sun, 10 Nov 2019 12:00:30 GMT", now)).To(Equal(30 * time.Second))
+	g.Expect(parseRetryAfter("Sun, 10 Nov 2019 11:00:00 GMT", now)).To(Equal(defaultRetryAfter))
+	g.Expect(parseRetryAfter("", now)).To(Equal(defaultRetryAfter))
+}
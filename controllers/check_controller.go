@@ -17,17 +17,30 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/mitchellh/hashstructure"
+	corev1 "k8s.io/api/core/v1"
 	apierrs "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	healthchecksio "github.com/kristofferahl/go-healthchecksio"
 	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
@@ -37,15 +50,50 @@ const (
 	finalizerName = "check.finalizers.monitoring.healthchecks.io"
 )
 
+// Reasons used for events recorded on a Check
+const (
+	EventReasonCreated             = "Created"
+	EventReasonAdopted             = "Adopted"
+	EventReasonUpdated             = "Updated"
+	EventReasonDeleted             = "Deleted"
+	EventReasonRetained            = "Retained"
+	EventReasonSyncFailed          = "SyncFailed"
+	EventReasonDeleteFailed        = "DeleteFailed"
+	EventReasonStatusChanged       = "StatusChanged"
+	EventReasonPingURLTargetFailed = "PingURLTargetFailed"
+)
+
 // CheckReconciler reconciles a Check object
 type CheckReconciler struct {
 	client.Client
 	Scheme            *runtime.Scheme
 	Log               logr.Logger
-	Hckio             *healthchecksio.Client
+	Recorder          record.EventRecorder
+	Hckio             HealthchecksAPI
+	Clients           *ClientCache
 	Clock             Clock
 	ReconcileInterval time.Duration
 	NamePrefix        string
+	OwnershipTag      string
+	DeletionPolicy    string
+
+	// Notifications enqueues Checks when a notification is received from healthchecks.io
+	Notifications <-chan event.GenericEvent
+
+	// Poller provides the status of checks, avoiding a read per Check
+	Poller *StatusPoller
+
+	// Limiter tells how long to back off when calls to healthchecks.io are rate limited or failing
+	Limiter *APILimiter
+
+	// NewAPI creates the HealthchecksAPI of Checks using a project or an API key Secret, a HealthchecksioAPI when nil
+	NewAPI APIFactory
+
+	// APIReader reads Secrets and ConfigMaps from the API server, they are not cached
+	APIReader client.Reader
+
+	// pingURLTargets holds the informers of the Secrets and ConfigMaps labeled as ping url targets
+	pingURLTargets informers.SharedInformerFactory
 }
 
 // Clock enables mocking of time
@@ -70,6 +118,7 @@ func NewClock() Clock {
 
 // +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=checks,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=checks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile tries to reconcile the object
 func (r *CheckReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -104,12 +153,23 @@ func (r *CheckReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		// The object is being deleted
 		if containsString(check.ObjectMeta.Finalizers, finalizerName) {
 			// our finalizer is present, so lets handle any external dependency
-			if err := r.deleteExternalResources(&check); err != nil {
-				// if fail to delete the external dependency here, return with error
-				// so that it can be retried
-				return ctrl.Result{}, err
+			if r.deletionPolicy(&check) == monitoringv1alpha1.DeletionPolicyRetain {
+				if err := r.retainExternalResources(&check); err != nil {
+					r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonDeleteFailed, "Failed to detach healthcheck %s: %s", check.Status.ID, err)
+					return ctrl.Result{}, err
+				}
+				log.V(0).Info(fmt.Sprintf("retained healthcheck: %s", check.Status.ID))
+				r.Recorder.Eventf(&check, corev1.EventTypeNormal, EventReasonRetained, "Retained healthcheck %s", check.Status.ID)
+			} else {
+				if err := r.deleteExternalResources(&check); err != nil {
+					// if fail to delete the external dependency here, return with error
+					// so that it can be retried
+					r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonDeleteFailed, "Failed to delete healthcheck %s: %s", check.Status.ID, err)
+					return ctrl.Result{}, err
+				}
+				log.V(0).Info(fmt.Sprintf("deleted healthcheck: %s", check.Status.ID))
+				r.Recorder.Eventf(&check, corev1.EventTypeNormal, EventReasonDeleted, "Deleted healthcheck %s", check.Status.ID)
 			}
-			log.V(0).Info(fmt.Sprintf("deleted healthcheck: %s", check.Status.ID))
 
 			// remove our finalizer from the list and update it.
 			check.ObjectMeta.Finalizers = removeString(check.ObjectMeta.Finalizers, finalizerName)
@@ -117,32 +177,76 @@ func (r *CheckReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				return ctrl.Result{}, err
 			}
 			log.V(0).Info("removed finalizer for Check")
+			deleteCheckMetrics(check)
 		}
 
 		return ctrl.Result{}, nil
 	}
 
+	project, err := r.resolveProject(ctx, &check)
+	if err != nil {
+		reason := "APIKeyUnavailable"
+		if check.Spec.ProjectRef != nil {
+			reason = "ProjectUnavailable"
+		}
+		log.Error(err, "unable to resolve the healthchecksio project for the Check")
+		r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonSyncFailed, "Failed to resolve project: %s", err)
+		r.updateSyncFailedStatus(ctx, &check, reason, err)
+		return ctrl.Result{}, err
+	}
+	desired := project.applyDefaults(check)
+
 	channels := make([]string, 0)
-	if len(check.Spec.Channels) > 0 {
-		allChannels, err := r.Hckio.GetAllChannels()
+	unresolved := 0
+	channelsCondition := monitoringv1alpha1.Condition{
+		Type:   monitoringv1alpha1.ConditionChannelsResolved,
+		Status: metav1.ConditionTrue,
+		Reason: "NoChannels",
+	}
+	if len(desired.Spec.Channels) > 0 {
+		allChannels, err := r.getAllChannels(project)
+		if result, ok := r.backOff(ctx, &check, project, err); ok {
+			return result, nil
+		}
 		if err != nil {
 			log.Error(err, "healthchecksio returned an error when fetching channels")
+			r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonSyncFailed, "Failed to fetch channels: %s", err)
+			r.updateSyncFailedStatus(ctx, &check, "FetchChannelsFailed", err)
 			return ctrl.Result{}, err
 		}
-		channels = matchTargetChannels(check, allChannels...)
+		channels = matchTargetChannels(desired, allChannels...)
 		log.V(1).Info("fetched channels from healthchecksio")
+		channelsCondition = channelsResolvedCondition(desired, allChannels...)
+		unresolved = len(unresolvedChannels(desired, allChannels...))
 	}
+	setUnresolvedChannels(check, unresolved)
 
-	healthcheck, err := r.Hckio.Create(r.convertToHealthcheck(check, channels...))
+	healthcheck, appliedHash, synced, err := r.syncHealthcheck(project, desired, channels...)
+	if result, ok := r.backOff(ctx, &check, project, err); ok {
+		return result, nil
+	}
 	if err != nil {
 		log.Error(err, "healthchecksio returned an error when creating/updating healthcheck")
+		r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonSyncFailed, "Failed to create/update healthcheck: %s", err)
+		r.updateSyncFailedStatus(ctx, &check, "CreateOrUpdateFailed", err)
 		return ctrl.Result{}, err
 	}
-	log.V(0).Info(fmt.Sprintf("created/updated healthcheck: %s", healthcheck.ID()))
+	if synced != controllerutil.OperationResultNone {
+		log.V(0).Info(fmt.Sprintf("%s healthcheck: %s", synced, healthcheck.ID()))
+	} else {
+		log.V(1).Info(fmt.Sprintf("healthcheck unchanged, refreshed status: %s", healthcheck.ID()))
+	}
+	reason, message := syncEvent(check, healthcheck, synced)
 	log.V(2).Info(fmt.Sprintf("healthcheck %s, %v", healthcheck.ID(), healthcheck))
 
 	// Update the status based on the response
-	if r.updateCheckStatus(&check, *healthcheck) {
+	previousStatus := check.Status.Status
+	changed := r.updateCheckStatus(&check, *healthcheck, channelsCondition)
+	if appliedHash != "" && appliedHash != check.Status.AppliedSpecHash {
+		check.Status.AppliedSpecHash = appliedHash
+		changed = true
+	}
+	if changed {
 		if err := r.Status().Update(ctx, &check); err != nil {
 			log.Error(err, "unable to update Check status")
 			return ctrl.Result{}, err
@@ -152,14 +256,171 @@ func (r *CheckReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		log.V(1).Info("skipped update of the Check status")
 	}
 
+	// the events are only recorded once the status is stored, as a failed update is retried
+	if reason != "" {
+		r.Recorder.Event(&check, corev1.EventTypeNormal, reason, message)
+	}
+	if eventType, message := statusChangedEvent(previousStatus, *healthcheck); message != "" {
+		r.Recorder.Event(&check, eventType, EventReasonStatusChanged, message)
+	}
+
+	op, err := r.reconcilePingURLTarget(ctx, &check)
+	if err != nil {
+		log.Error(err, "unable to publish ping urls", "kind", check.Spec.PingURLTarget.Kind, "name", check.Spec.PingURLTarget.Name)
+		r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonPingURLTargetFailed, "Failed to publish ping urls to %s %s: %s", check.Spec.PingURLTarget.Kind, check.Spec.PingURLTarget.Name, err)
+		return ctrl.Result{}, err
+	}
+	if op != controllerutil.OperationResultNone {
+		log.V(1).Info(fmt.Sprintf("%s ping url target", op), "kind", check.Spec.PingURLTarget.Kind, "name", check.Spec.PingURLTarget.Name)
+	}
+
 	// TODO: requeue configurable or not at all?
 	return ctrl.Result{RequeueAfter: r.ReconcileInterval}, nil
 }
 
+// syncHealthcheck creates or updates the check in healthchecks.io when its desired state or generation
+// changed since it was last written, returning the hash of the written state and whether the check was
+// created or updated. Otherwise the check is read, from the poller when there is one, and an empty hash
+// is returned.
+func (r *CheckReconciler) syncHealthcheck(project checkProject, check monitoringv1alpha1.Check, channels ...string) (*healthchecksio.HealthcheckResponse, string, controllerutil.OperationResult, error) {
+	hash, hashErr := desiredHash(project, check, channels...)
+	unchanged := hashErr == nil && check.Status.AppliedSpecHash == hash && check.Status.ObservedGeneration == check.ObjectMeta.Generation
+
+	if unchanged && check.Status.ID != "" {
+		if r.Poller != nil {
+			if healthcheck, ok := r.Poller.Check(project.client, check.Status.ID); ok {
+				return healthcheck, "", controllerutil.OperationResultNone, nil
+			}
+		}
+
+		healthcheck, err := project.client.Get(check.Status.ID)
+		if err != nil {
+			return nil, "", controllerutil.OperationResultNone, err
+		}
+		// a check deleted from healthchecks.io is created again
+		if healthcheck != nil {
+			if r.Poller != nil {
+				r.Poller.Store(project.client, healthcheck)
+			}
+			return healthcheck, "", controllerutil.OperationResultNone, nil
+		}
+	}
+
+	healthcheck, op, err := project.upsert(check, channels...)
+	if err != nil {
+		return nil, "", controllerutil.OperationResultNone, err
+	}
+
+	if r.Poller != nil {
+		r.Poller.Store(project.client, healthcheck)
+	}
+
+	return healthcheck, hash, op, nil
+}
+
+// syncEvent returns the reason and message of the event recording the sync of the check, or an empty
+// reason when the check was not written to healthchecks.io
+func syncEvent(check monitoringv1alpha1.Check, healthcheck *healthchecksio.HealthcheckResponse, op controllerutil.OperationResult) (string, string) {
+	switch {
+	case op == controllerutil.OperationResultNone:
+		return "", ""
+	case op == controllerutil.OperationResultCreated:
+		return EventReasonCreated, fmt.Sprintf("Created healthcheck %s", healthcheck.ID())
+	case check.Spec.AdoptID != "" && check.Status.ID != healthcheck.ID():
+		return EventReasonAdopted, fmt.Sprintf("Adopted healthcheck %s", healthcheck.ID())
+	default:
+		return EventReasonUpdated, fmt.Sprintf("Updated healthcheck %s", healthcheck.ID())
+	}
+}
+
+// statusChangedEvent returns the type and message of the event recording the transition of the check
+// from the previous status, or an empty message when the status did not change
+func statusChangedEvent(previous string, healthcheck healthchecksio.HealthcheckResponse) (string, string) {
+	if previous == "" || previous == healthcheck.Status {
+		return "", ""
+	}
+
+	eventType := corev1.EventTypeNormal
+	if healthcheck.Status == "down" {
+		eventType = corev1.EventTypeWarning
+	}
+	return eventType, fmt.Sprintf("Healthcheck status changed from %s to %s", previous, healthcheck.Status)
+}
+
+// backOff requeues the Check once the limiter lets calls to the API of the project through again, when
+// err was caused by the API being rate limited or unavailable. The Check is marked as not synced, and
+// as ApiUnavailable while the circuit of the API is open. A Check throttled by the limiter itself, before
+// the API was called, is only requeued once the limiter has a token for it.
+func (r *CheckReconciler) backOff(ctx context.Context, check *monitoringv1alpha1.Check, project checkProject, err error) (ctrl.Result, bool) {
+	if err == nil || r.Limiter == nil {
+		return ctrl.Result{}, false
+	}
+
+	var limited *RateLimitedError
+	if errors.As(err, &limited) {
+		r.Log.V(1).Info(fmt.Sprintf("requests to healthchecksio queued, retrying after %s", limited.RetryAfter.Round(time.Second)), "check", fmt.Sprintf("%s/%s", check.Namespace, check.Name))
+		return ctrl.Result{RequeueAfter: limited.RetryAfter}, true
+	}
+
+	wait, open := r.Limiter.Backoff(project.client.BaseURL(), project.client.Account())
+	if wait <= 0 {
+		return ctrl.Result{}, false
+	}
+
+	reason := "RateLimited"
+	if open {
+		reason = "ApiUnavailable"
+		r.setCondition(check, monitoringv1alpha1.Condition{
+			Type:    monitoringv1alpha1.ConditionAPIUnavailable,
+			Status:  metav1.ConditionTrue,
+			Reason:  "CircuitOpen",
+			Message: err.Error(),
+		})
+	}
+
+	r.Log.V(0).Info(fmt.Sprintf("backing off from healthchecksio for %s", wait.Round(time.Second)), "check", fmt.Sprintf("%s/%s", check.Namespace, check.Name), "reason", reason)
+	r.Recorder.Eventf(check, corev1.EventTypeWarning, EventReasonSyncFailed, "Backing off from healthchecks.io for %s: %s", wait.Round(time.Second), err)
+	r.updateSyncFailedStatus(ctx, check, reason, err)
+
+	return ctrl.Result{RequeueAfter: wait}, true
+}
+
+// getAllChannels returns the channels of the project, as last polled when there is a poller
+func (r *CheckReconciler) getAllChannels(project checkProject) ([]*healthchecksio.HealthcheckChannelResponse, error) {
+	if r.Poller != nil {
+		if channels, ok := r.Poller.Channels(project.client); ok {
+			return channels, nil
+		}
+	}
+
+	return project.client.GetAllChannels()
+}
+
+// desiredHash returns a hash of the desired state of the check in healthchecks.io
+func desiredHash(project checkProject, check monitoringv1alpha1.Check, channels ...string) (string, error) {
+	hash, err := hashstructure.Hash(struct {
+		Client      string
+		AdoptID     string
+		Healthcheck healthchecksio.Healthcheck
+	}{
+		Client:      project.client.Key(),
+		AdoptID:     check.Spec.AdoptID,
+		Healthcheck: project.convertToHealthcheck(check, channels...),
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(hash, 16), nil
+}
+
 func (r *CheckReconciler) convertToHealthcheck(check monitoringv1alpha1.Check, channels ...string) healthchecksio.Healthcheck {
+	return r.defaultProject().convertToHealthcheck(check, channels...)
+}
+
+func (p checkProject) convertToHealthcheck(check monitoringv1alpha1.Check, channels ...string) healthchecksio.Healthcheck {
 	name := fmt.Sprintf("%s/%s", check.Namespace, check.Name)
-	if r.NamePrefix != "" {
-		name = fmt.Sprintf("%s/%s", r.NamePrefix, name)
+	if p.namePrefix != "" {
+		name = fmt.Sprintf("%s/%s", p.namePrefix, name)
 	}
 
 	timeout := 0
@@ -172,13 +433,18 @@ func (r *CheckReconciler) convertToHealthcheck(check monitoringv1alpha1.Check, c
 		graceperiod = int(*check.Spec.GracePeriod)
 	}
 
+	tags := check.Spec.Tags
+	if p.ownershipTag != "" && !containsString(tags, p.ownershipTag) {
+		tags = append(append([]string{}, tags...), p.ownershipTag)
+	}
+
 	return healthchecksio.Healthcheck{
 		Name:     name,
 		Schedule: check.Spec.Schedule,
 		Timezone: check.Spec.Timezone,
 		Timeout:  timeout,
 		Grace:    graceperiod,
-		Tags:     strings.Join(check.Spec.Tags, " "),
+		Tags:     strings.Join(tags, " "),
 		Channels: strings.Join(channels, ","),
 		Unique:   []string{"name"},
 	}
@@ -209,7 +475,7 @@ func matchTargetChannels(check monitoringv1alpha1.Check, allChannels ...*healthc
 	return channels
 }
 
-func (r *CheckReconciler) updateCheckStatus(check *monitoringv1alpha1.Check, healthcheck healthchecksio.HealthcheckResponse) bool {
+func (r *CheckReconciler) updateCheckStatus(check *monitoringv1alpha1.Check, healthcheck healthchecksio.HealthcheckResponse, conditions ...monitoringv1alpha1.Condition) bool {
 	changed := false
 
 	before, err := hashstructure.Hash(check.Status, nil)
@@ -226,6 +492,17 @@ func (r *CheckReconciler) updateCheckStatus(check *monitoringv1alpha1.Check, hea
 	check.Status.Pings = &pings
 	check.Status.LastPing = parseTimestamp(healthcheck.LastPing)
 
+	r.setCondition(check, monitoringv1alpha1.Condition{
+		Type:   monitoringv1alpha1.ConditionSynced,
+		Status: metav1.ConditionTrue,
+		Reason: "Synced",
+	})
+	r.setCondition(check, readyCondition(healthcheck))
+	monitoringv1alpha1.RemoveCondition(&check.Status.Conditions, monitoringv1alpha1.ConditionAPIUnavailable)
+	for _, c := range conditions {
+		r.setCondition(check, c)
+	}
+
 	after, err := hashstructure.Hash(check.Status, nil)
 	if err != nil {
 		changed = true
@@ -242,14 +519,159 @@ func (r *CheckReconciler) updateCheckStatus(check *monitoringv1alpha1.Check, hea
 	return changed
 }
 
+// updateSyncFailedStatus marks the Check as not synced. The status is only updated when the condition
+// changed, as a failing sync is retried. Failing to update the status is only logged as the original
+// error is returned by Reconcile.
+func (r *CheckReconciler) updateSyncFailedStatus(ctx context.Context, check *monitoringv1alpha1.Check, reason string, syncErr error) {
+	before, beforeErr := hashstructure.Hash(check.Status, nil)
+
+	r.setCondition(check, monitoringv1alpha1.Condition{
+		Type:    monitoringv1alpha1.ConditionSynced,
+		Status:  metav1.ConditionFalse,
+		Reason:  reason,
+		Message: syncErr.Error(),
+	})
+
+	if after, err := hashstructure.Hash(check.Status, nil); beforeErr == nil && err == nil && before == after {
+		return
+	}
+
+	if err := r.Status().Update(ctx, check); err != nil {
+		r.Log.Error(err, "unable to update Check status", "check", fmt.Sprintf("%s/%s", check.Namespace, check.Name))
+	}
+}
+
+func (r *CheckReconciler) setCondition(check *monitoringv1alpha1.Check, condition monitoringv1alpha1.Condition) {
+	condition.ObservedGeneration = check.ObjectMeta.Generation
+	condition.LastTransitionTime = *r.Clock.Now()
+	monitoringv1alpha1.SetCondition(&check.Status.Conditions, condition)
+}
+
+func readyCondition(healthcheck healthchecksio.HealthcheckResponse) monitoringv1alpha1.Condition {
+	if healthcheck.Status == "down" {
+		return monitoringv1alpha1.Condition{
+			Type:    monitoringv1alpha1.ConditionReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Down",
+			Message: "the check is down",
+		}
+	}
+
+	return monitoringv1alpha1.Condition{
+		Type:    monitoringv1alpha1.ConditionReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  strings.Title(healthcheck.Status),
+		Message: fmt.Sprintf("the check is %s", healthcheck.Status),
+	}
+}
+
+func channelsResolvedCondition(check monitoringv1alpha1.Check, allChannels ...*healthchecksio.HealthcheckChannelResponse) monitoringv1alpha1.Condition {
+	unresolved := unresolvedChannels(check, allChannels...)
+	if len(unresolved) > 0 {
+		return monitoringv1alpha1.Condition{
+			Type:    monitoringv1alpha1.ConditionChannelsResolved,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ChannelsNotFound",
+			Message: fmt.Sprintf("no matching channels found for: %s", strings.Join(unresolved, ", ")),
+		}
+	}
+
+	return monitoringv1alpha1.Condition{
+		Type:   monitoringv1alpha1.ConditionChannelsResolved,
+		Status: metav1.ConditionTrue,
+		Reason: "Resolved",
+	}
+}
+
+func unresolvedChannels(check monitoringv1alpha1.Check, allChannels ...*healthchecksio.HealthcheckChannelResponse) []string {
+	unresolved := make([]string, 0)
+
+	for _, channelKindName := range check.Spec.Channels {
+		if channelKindName == "*" {
+			continue
+		}
+
+		p := strings.Split(channelKindName, "/")
+		kind := p[0]
+		name := ""
+		if len(p) == 2 {
+			name = p[1]
+		}
+
+		found := false
+		for _, c := range allChannels {
+			if isTargetChannel(c, name, kind) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unresolved = append(unresolved, channelKindName)
+		}
+	}
+
+	return unresolved
+}
+
 // Delete any external resources associated with the check.
 // Ensure that delete implementation is idempotent and safe to
 // invoke multiple times for same object.
 func (r *CheckReconciler) deleteExternalResources(check *monitoringv1alpha1.Check) error {
-	_, err := r.Hckio.Delete(check.Status.ID)
+	project, err := r.resolveProject(context.Background(), check)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			// the project or api key secret is gone, most likely deleted along with the namespace,
+			// retrying would block the deletion of the check forever
+			r.Log.V(0).Info(fmt.Sprintf("project or api key secret not found, healthcheck %s will not be deleted", check.Status.ID))
+			r.Recorder.Eventf(check, corev1.EventTypeWarning, EventReasonDeleteFailed, "Project or API key secret not found, healthcheck %s will not be deleted", check.Status.ID)
+			return nil
+		}
+		return err
+	}
+
+	_, err = project.client.Delete(check.Status.ID)
+	if err != nil {
+		if statusCode(err) == http.StatusNotFound {
+			r.Log.V(1).Info(fmt.Sprintf("healthcheck not found or already deleted (status=%d)", statusCode(err)))
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Detach the external resources associated with the check from the operator,
+// leaving them in place. The ownership tag is removed, or without an ownership tag
+// the name prefix is stripped from the name, so the garbage collector no longer
+// considers them owned.
+func (r *CheckReconciler) retainExternalResources(check *monitoringv1alpha1.Check) error {
+	if check.Status.ID == "" {
+		return nil
+	}
+
+	project, err := r.resolveProject(context.Background(), check)
+	if err != nil {
+		if apierrs.IsNotFound(err) {
+			r.Log.V(0).Info(fmt.Sprintf("project or api key secret not found, healthcheck %s will not be detached", check.Status.ID))
+			r.Recorder.Eventf(check, corev1.EventTypeWarning, EventReasonDeleteFailed, "Project or API key secret not found, healthcheck %s will not be detached", check.Status.ID)
+			return nil
+		}
+		return err
+	}
+
+	if project.ownershipTag == "" && project.namePrefix == "" {
+		return nil
+	}
+
+	if project.ownershipTag != "" {
+		_, err = project.client.SetTags(check.Status.ID, removeString(project.applyDefaults(*check).Spec.Tags, project.ownershipTag))
+	} else {
+		_, err = project.client.Update(check.Status.ID, healthchecksio.Healthcheck{Name: fmt.Sprintf("%s/%s", check.Namespace, check.Name)})
+	}
 	if err != nil {
-		if err, ok := err.(*healthchecksio.APIError); ok && err.StatusCode() == 404 {
-			r.Log.V(1).Info(fmt.Sprintf("healthcheck not found or already deleted (status=%s)", err.Status()))
+		if statusCode(err) == http.StatusNotFound {
+			r.Log.V(1).Info(fmt.Sprintf("healthcheck not found or already deleted (status=%d)", statusCode(err)))
 			return nil
 		}
 		return err
@@ -257,6 +679,17 @@ func (r *CheckReconciler) deleteExternalResources(check *monitoringv1alpha1.Chec
 	return nil
 }
 
+// deletionPolicy returns the deletion policy of the check, falling back to the policy of the operator
+func (r *CheckReconciler) deletionPolicy(check *monitoringv1alpha1.Check) string {
+	if check.Spec.DeletionPolicy != "" {
+		return check.Spec.DeletionPolicy
+	}
+	if r.DeletionPolicy != "" {
+		return r.DeletionPolicy
+	}
+	return monitoringv1alpha1.DeletionPolicyDelete
+}
+
 func parseTimestamp(ts string) *metav1.Time {
 	var lastPing metav1.Time
 	lp, err := time.Parse(time.RFC3339, ts)
@@ -287,6 +720,17 @@ func removeString(slice []string, s string) (result []string) {
 	return
 }
 
+// SplitList splits a comma separated list, dropping empty items
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func isTargetChannel(c *healthchecksio.HealthcheckChannelResponse, name, kind string) bool {
 	if name == "" {
 		return c.Kind == kind
@@ -304,7 +748,67 @@ func ignoreNotFound(err error) error {
 
 // SetupWithManager hooks up the controller/reconciler
 func (r *CheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&monitoringv1alpha1.Check{}).
-		Complete(r)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&monitoringv1alpha1.Check{})
+
+	if r.Notifications != nil {
+		if err := mgr.GetFieldIndexer().IndexField(&monitoringv1alpha1.Check{}, StatusIDField, IndexStatusID); err != nil {
+			return err
+		}
+		b = b.Watches(&source.Channel{Source: r.Notifications}, &handler.EnqueueRequestForObject{})
+	}
+
+	c, err := b.Build(r)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+
+	// only the ping url targets created by the operator are listed and watched, not every Secret and ConfigMap
+	factory := pingURLTargetInformers(clientset)
+	r.pingURLTargets = factory
+	owner := &handler.EnqueueRequestForOwner{OwnerType: &monitoringv1alpha1.Check{}, IsController: true}
+	for _, informer := range []toolscache.SharedIndexInformer{factory.Core().V1().Secrets().Informer(), factory.Core().V1().ConfigMaps().Informer()} {
+		if err := c.Watch(&source.Informer{Informer: informer}, owner); err != nil {
+			return err
+		}
+	}
+
+	// Checks are synced again when their project or API key Secret changes
+	indexes := []struct {
+		obj   runtime.Object
+		field string
+		fn    client.IndexerFunc
+	}{
+		{&monitoringv1alpha1.Check{}, ProjectRefField, IndexProjectRef},
+		{&monitoringv1alpha1.Check{}, APIKeySecretRefField, IndexAPIKeySecretRef},
+		{&monitoringv1alpha1.HealthchecksProject{}, APIKeySecretRefField, IndexAPIKeySecretRef},
+		{&monitoringv1alpha1.ClusterHealthchecksProject{}, APIKeySecretRefField, IndexAPIKeySecretRef},
+	}
+	for _, index := range indexes {
+		if err := mgr.GetFieldIndexer().IndexField(index.obj, index.field, index.fn); err != nil {
+			return err
+		}
+	}
+	projects := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.projectChecks)}
+	for _, project := range []runtime.Object{&monitoringv1alpha1.HealthchecksProject{}, &monitoringv1alpha1.ClusterHealthchecksProject{}} {
+		if err := c.Watch(&source.Kind{Type: project}, projects); err != nil {
+			return err
+		}
+	}
+	secrets := apiKeySecretInformer(clientset)
+	if err := c.Watch(&source.Informer{Informer: secrets}, &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.secretChecks)}); err != nil {
+		return err
+	}
+
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		factory.Start(stop)
+		go secrets.Run(stop)
+		<-stop
+		return nil
+	}))
 }
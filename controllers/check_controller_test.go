@@ -3,16 +3,26 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	. "github.com/onsi/gomega"
@@ -59,6 +69,15 @@ func TestCheckController_RemoveString(t *testing.T) {
 	g.Expect(actual).To(Equal(expected))
 }
 
+func TestCheckController_SplitList(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+
+	// Act & assert
+	g.Expect(SplitList("email, slack/ops,,")).To(Equal([]string{"email", "slack/ops"}))
+	g.Expect(SplitList(" , ")).To(BeEmpty())
+}
+
 func TestCheckController_IsTargetChannel(t *testing.T) {
 	// Arrange
 	g := NewGomegaWithT(t)
@@ -150,6 +169,187 @@ func TestCheckController_DeleteExternalResources_Error(t *testing.T) {
 	ctx.t.Expect(err).To(HaveOccurred(), "hckio client returns with error")
 }
 
+func TestCheckController_DeleteExternalResources_APIKeySecretNotFound(t *testing.T) {
+	// Arrange
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: monitoringv1alpha1.CheckSpec{
+			APIKeySecretRef: &monitoringv1alpha1.SecretKeyReference{Name: "api-key", Key: "key"},
+		},
+	}
+
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(check),
+	)
+	defer func() { ctx.Close() }()
+
+	// Act
+	err := ctx.Reconciler.deleteExternalResources(check)
+
+	// Asert
+	ctx.t.Expect(err).ToNot(HaveOccurred(), "api key secret is gone, retrying won't help")
+	ctx.t.Expect(ctx.Events()).To(ConsistOf(HavePrefix("Warning DeleteFailed")))
+}
+
+func TestCheckController_ResolveProject_APIKeySecretRef(t *testing.T) {
+	// Arrange
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-key",
+			Namespace: "bar",
+		},
+		Data: map[string][]byte{
+			"key": []byte("team-api-key"),
+		},
+	}
+
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(check, secret),
+	)
+	defer func() { ctx.Close() }()
+
+	// Act & assert
+	p, err := ctx.Reconciler.resolveProject(context.TODO(), check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(p.client).To(BeIdenticalTo(ctx.Reconciler.Hckio), "defaults to the operator client")
+
+	check.Spec.APIKeySecretRef = &monitoringv1alpha1.SecretKeyReference{Name: "api-key", Key: "key"}
+	p, err = ctx.Reconciler.resolveProject(context.TODO(), check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(p.client.(*HealthchecksioAPI).client.APIKey).To(Equal("team-api-key"))
+
+	ctx.Reconciler.NewAPI = func(client *healthchecksio.Client, opts ClientOptions) HealthchecksAPI {
+		return &recordingAPI{HealthchecksAPI: NewHealthchecksioAPIWithOptions(client, opts)}
+	}
+	p, err = ctx.Reconciler.resolveProject(context.TODO(), check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(p.client).To(BeAssignableToTypeOf(&recordingAPI{}), "created by the api factory")
+
+	check.Spec.APIKeySecretRef = &monitoringv1alpha1.SecretKeyReference{Name: "api-key", Key: "missing"}
+	_, err = ctx.Reconciler.resolveProject(context.TODO(), check)
+	ctx.t.Expect(err).To(HaveOccurred())
+
+	check.Spec.APIKeySecretRef = &monitoringv1alpha1.SecretKeyReference{Name: "missing", Key: "key"}
+	_, err = ctx.Reconciler.resolveProject(context.TODO(), check)
+	ctx.t.Expect(err).To(HaveOccurred())
+}
+
+func TestCheckController_IndexReferences(t *testing.T) {
+	g := NewGomegaWithT(t)
+	check := &monitoringv1alpha1.Check{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"}}
+	g.Expect(IndexProjectRef(check)).To(BeEmpty())
+	g.Expect(IndexAPIKeySecretRef(check)).To(BeEmpty())
+
+	check.Spec.ProjectRef = &monitoringv1alpha1.ProjectReference{Name: "team"}
+	check.Spec.APIKeySecretRef = &monitoringv1alpha1.SecretKeyReference{Name: "api-key", Key: "key"}
+	g.Expect(IndexProjectRef(check)).To(ConsistOf("HealthchecksProject/bar/team"))
+	g.Expect(IndexAPIKeySecretRef(check)).To(ConsistOf("bar/api-key"))
+
+	check.Spec.ProjectRef = &monitoringv1alpha1.ProjectReference{Kind: "ClusterHealthchecksProject", Name: "shared"}
+	g.Expect(IndexProjectRef(check)).To(ConsistOf("ClusterHealthchecksProject//shared"))
+
+	g.Expect(IndexAPIKeySecretRef(&monitoringv1alpha1.HealthchecksProject{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "bar"},
+		Spec:       monitoringv1alpha1.HealthchecksProjectSpec{APIKeySecretRef: newProjectSecretKeyReference("")},
+	})).To(ConsistOf("bar/project-api-key"))
+	g.Expect(IndexAPIKeySecretRef(&monitoringv1alpha1.ClusterHealthchecksProject{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+		Spec:       monitoringv1alpha1.HealthchecksProjectSpec{APIKeySecretRef: newProjectSecretKeyReference("ops")},
+	})).To(ConsistOf("ops/project-api-key"))
+}
+
+func TestCheckController_ReferencingChecks(t *testing.T) {
+	// Arrange
+	check := func(name string, projectRef *monitoringv1alpha1.ProjectReference, secretRef *monitoringv1alpha1.SecretKeyReference) *monitoringv1alpha1.Check {
+		return &monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "bar"},
+			Spec:       monitoringv1alpha1.CheckSpec{ProjectRef: projectRef, APIKeySecretRef: secretRef},
+		}
+	}
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(
+			check("default", nil, nil),
+			check("team", &monitoringv1alpha1.ProjectReference{Name: "team"}, nil),
+			check("shared", &monitoringv1alpha1.ProjectReference{Kind: "ClusterHealthchecksProject", Name: "shared"}, nil),
+			check("secret", nil, &monitoringv1alpha1.SecretKeyReference{Name: "project-api-key", Key: "api-key"}),
+			&monitoringv1alpha1.HealthchecksProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "bar"},
+				Spec:       monitoringv1alpha1.HealthchecksProjectSpec{APIKeySecretRef: newProjectSecretKeyReference("")},
+			},
+			&monitoringv1alpha1.ClusterHealthchecksProject{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared"},
+				Spec:       monitoringv1alpha1.HealthchecksProjectSpec{APIKeySecretRef: newProjectSecretKeyReference("ops")},
+			},
+		),
+	)
+	defer func() { ctx.Close() }()
+	ctx.Reconciler.Client = &fieldIndexedClient{Client: ctx.Reconciler.Client, indexes: map[string]client.IndexerFunc{
+		ProjectRefField:      IndexProjectRef,
+		APIKeySecretRefField: IndexAPIKeySecretRef,
+	}}
+	requests := func(names ...string) []reconcile.Request {
+		requests := make([]reconcile.Request, 0)
+		for _, name := range names {
+			requests = append(requests, NewReconcileRequest(name, "bar"))
+		}
+		return requests
+	}
+	mapObject := func(obj runtime.Object) handler.MapObject {
+		m, _ := meta.Accessor(obj)
+		return handler.MapObject{Meta: m, Object: obj}
+	}
+
+	// Act & assert
+	ctx.t.Expect(ctx.Reconciler.projectChecks(mapObject(&monitoringv1alpha1.HealthchecksProject{ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "bar"}}))).
+		To(ConsistOf(requests("team")))
+	ctx.t.Expect(ctx.Reconciler.projectChecks(mapObject(&monitoringv1alpha1.HealthchecksProject{ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "other"}}))).
+		To(BeEmpty())
+	ctx.t.Expect(ctx.Reconciler.projectChecks(mapObject(&monitoringv1alpha1.ClusterHealthchecksProject{ObjectMeta: metav1.ObjectMeta{Name: "shared"}}))).
+		To(ConsistOf(requests("shared")))
+	ctx.t.Expect(ctx.Reconciler.secretChecks(mapObject(newProjectAPIKeySecret("bar")))).
+		To(ConsistOf(requests("team", "secret")))
+	ctx.t.Expect(ctx.Reconciler.secretChecks(mapObject(newProjectAPIKeySecret("ops")))).
+		To(ConsistOf(requests("shared")))
+}
+
+func TestCheckController_APIKeySecretInformer(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	labeled := newProjectAPIKeySecret("bar")
+	labeled.Labels = map[string]string{LabelAPIKeySecret: "true"}
+	clientset := fakeclientset.NewSimpleClientset(labeled, newProjectAPIKeySecret("ops"))
+	informer := apiKeySecretInformer(clientset)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// Act
+	go informer.Run(stop)
+	g.Expect(toolscache.WaitForCacheSync(stop, informer.HasSynced)).To(BeTrue())
+
+	// Assert, only the metadata of the Secret is kept
+	obj, exists, err := informer.GetStore().GetByKey("bar/project-api-key")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeTrue())
+	g.Expect(obj.(*corev1.Secret).Data).To(BeNil())
+
+	// Assert, Secrets without the label are not watched
+	_, exists, err = informer.GetStore().GetByKey("ops/project-api-key")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(exists).To(BeFalse())
+}
+
 func TestCheckController_ConvertCheckToHealthcheck(t *testing.T) {
 	g := NewGomegaWithT(t)
 	r := &CheckReconciler{}
@@ -229,6 +429,167 @@ func TestCheckController_ConvertCheckToHealthcheck_NamePrefix(t *testing.T) {
 	})).To(Equal(healthchecksio.Healthcheck{Name: np + "/" + namespace + "/" + name, Unique: []string{"name"}}))
 }
 
+func TestCheckController_ConvertCheckToHealthcheck_OwnershipTag(t *testing.T) {
+	g := NewGomegaWithT(t)
+	r := &CheckReconciler{OwnershipTag: "k8s-operator"}
+
+	g.Expect(r.convertToHealthcheck(monitoringv1alpha1.Check{})).
+		To(Equal(healthchecksio.Healthcheck{Name: "/", Tags: "k8s-operator", Unique: []string{"name"}}))
+
+	g.Expect(r.convertToHealthcheck(monitoringv1alpha1.Check{
+		Spec: monitoringv1alpha1.CheckSpec{
+			Tags: []string{"foo", "k8s-operator"},
+		},
+	})).To(Equal(healthchecksio.Healthcheck{Name: "/", Tags: "foo k8s-operator", Unique: []string{"name"}}))
+}
+
+func TestCheckController_DeletionPolicy(t *testing.T) {
+	g := NewGomegaWithT(t)
+	retain := &monitoringv1alpha1.Check{Spec: monitoringv1alpha1.CheckSpec{DeletionPolicy: monitoringv1alpha1.DeletionPolicyRetain}}
+	del := &monitoringv1alpha1.Check{Spec: monitoringv1alpha1.CheckSpec{DeletionPolicy: monitoringv1alpha1.DeletionPolicyDelete}}
+
+	g.Expect((&CheckReconciler{}).deletionPolicy(&monitoringv1alpha1.Check{})).To(Equal(monitoringv1alpha1.DeletionPolicyDelete))
+	g.Expect((&CheckReconciler{DeletionPolicy: monitoringv1alpha1.DeletionPolicyRetain}).deletionPolicy(&monitoringv1alpha1.Check{})).To(Equal(monitoringv1alpha1.DeletionPolicyRetain))
+	g.Expect((&CheckReconciler{}).deletionPolicy(retain)).To(Equal(monitoringv1alpha1.DeletionPolicyRetain))
+	g.Expect((&CheckReconciler{DeletionPolicy: monitoringv1alpha1.DeletionPolicyRetain}).deletionPolicy(del)).To(Equal(monitoringv1alpha1.DeletionPolicyDelete))
+}
+
+func TestCheckController_RetainExternalResources(t *testing.T) {
+	// Arrange
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		body = fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, b)
+		res.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: monitoringv1alpha1.CheckSpec{
+			Tags: []string{"foo"},
+		},
+		Status: monitoringv1alpha1.CheckStatus{
+			ID: "c1",
+		},
+	}
+
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(check),
+		WithHckioBaseURL(server.URL),
+	)
+	defer func() { ctx.Close() }()
+	ctx.Reconciler.OwnershipTag = "k8s-operator"
+
+	// Act
+	err := ctx.Reconciler.retainExternalResources(check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(body).To(Equal(`POST /checks/c1 {"tags":"foo"}`))
+}
+
+func TestCheckController_RetainExternalResources_RemovesLastTag(t *testing.T) {
+	// Arrange
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		body = fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, b)
+		res.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Status: monitoringv1alpha1.CheckStatus{
+			ID: "c1",
+		},
+	}
+
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(check),
+		WithHckioBaseURL(server.URL),
+	)
+	defer func() { ctx.Close() }()
+	ctx.Reconciler.OwnershipTag = "k8s-operator"
+
+	// Act
+	err := ctx.Reconciler.retainExternalResources(check)
+
+	// Assert, the tags are sent empty so the ownership tag is removed
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(body).To(Equal(`POST /checks/c1 {"tags":""}`))
+}
+
+func TestCheckController_RetainExternalResources_NoOwnershipTag(t *testing.T) {
+	// Arrange
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Status: monitoringv1alpha1.CheckStatus{
+			ID: "c1",
+		},
+	}
+
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(check),
+	)
+	defer func() { ctx.Close() }()
+
+	// Act
+	err := ctx.Reconciler.retainExternalResources(check)
+
+	// Assert, the fake server fails any request as no responses are registered
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestCheckController_RetainExternalResources_NamePrefix(t *testing.T) {
+	// Arrange
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		body = fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, b)
+		res.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Status: monitoringv1alpha1.CheckStatus{
+			ID: "c1",
+		},
+	}
+
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(check),
+		WithHckioBaseURL(server.URL),
+	)
+	defer func() { ctx.Close() }()
+	ctx.Reconciler.NamePrefix = "cluster-1"
+
+	// Act
+	err := ctx.Reconciler.retainExternalResources(check)
+
+	// Assert, without an ownership tag the check is renamed out of the prefix
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(body).To(Equal(`POST /checks/c1 {"name":"bar/foo"}`))
+}
+
 func TestCheckController_MatchChannelsToChannels(t *testing.T) {
 	g := NewGomegaWithT(t)
 
@@ -344,6 +705,9 @@ func TestCheckController_UpdateCheckStatusFromHealtcheck_Unchanged(t *testing.T)
 		Pings:     pings,
 	}
 
+	ready := readyCondition(*res)
+	ready.LastTransitionTime = serverTime
+
 	check := monitoringv1alpha1.Check{
 		Status: monitoringv1alpha1.CheckStatus{
 			ID:       id,
@@ -351,6 +715,15 @@ func TestCheckController_UpdateCheckStatusFromHealtcheck_Unchanged(t *testing.T)
 			Status:   res.Status,
 			LastPing: &serverTime,
 			Pings:    &pings32,
+			Conditions: []monitoringv1alpha1.Condition{
+				{
+					Type:               monitoringv1alpha1.ConditionSynced,
+					Status:             metav1.ConditionTrue,
+					Reason:             "Synced",
+					LastTransitionTime: serverTime,
+				},
+				ready,
+			},
 		},
 	}
 
@@ -361,6 +734,101 @@ func TestCheckController_UpdateCheckStatusFromHealtcheck_Unchanged(t *testing.T)
 	r.t.Expect(changed).To(BeFalse())
 }
 
+func TestCheckController_ReadyCondition(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(readyCondition(healthchecksio.HealthcheckResponse{Status: "up"}).Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(readyCondition(healthchecksio.HealthcheckResponse{Status: "new"}).Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(readyCondition(healthchecksio.HealthcheckResponse{Status: "grace"}).Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(readyCondition(healthchecksio.HealthcheckResponse{Status: "down"}).Status).To(Equal(metav1.ConditionFalse))
+}
+
+func TestCheckController_UnresolvedChannels(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	channels := []*healthchecksio.HealthcheckChannelResponse{
+		&healthchecksio.HealthcheckChannelResponse{
+			ID:   "1",
+			Name: "email-1",
+			Kind: "email",
+		},
+	}
+
+	g.Expect(unresolvedChannels(monitoringv1alpha1.Check{}, channels...)).To(BeEmpty())
+	g.Expect(unresolvedChannels(monitoringv1alpha1.Check{
+		Spec: monitoringv1alpha1.CheckSpec{
+			Channels: []string{"*"},
+		},
+	}, channels...)).To(BeEmpty())
+	g.Expect(unresolvedChannels(monitoringv1alpha1.Check{
+		Spec: monitoringv1alpha1.CheckSpec{
+			Channels: []string{"email", "email/email-1"},
+		},
+	}, channels...)).To(BeEmpty())
+	g.Expect(unresolvedChannels(monitoringv1alpha1.Check{
+		Spec: monitoringv1alpha1.CheckSpec{
+			Channels: []string{"email/email-2", "sms", "email"},
+		},
+	}, channels...)).To(Equal([]string{"email/email-2", "sms"}))
+}
+
+func TestCheckController_UpdateCheckStatusFromHealtcheck_Conditions(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	serverTime := metav1.NewTime(now)
+	later := metav1.NewTime(now.Add(time.Minute))
+	clock := serverTime
+
+	r := NewCheckReconcilerTest(t, WithReconcilerClock(func() *metav1.Time {
+		return &clock
+	}))
+
+	check := monitoringv1alpha1.Check{}
+	res := &healthchecksio.HealthcheckResponse{
+		UpdateURL: "update/id",
+		Status:    "up",
+	}
+
+	// Act
+	r.Reconciler.updateCheckStatus(&check, *res, channelsResolvedCondition(check))
+
+	// Assert
+	r.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionSynced)).To(BeTrue())
+	r.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionReady)).To(BeTrue())
+	r.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionChannelsResolved)).To(BeTrue())
+
+	// Act
+	clock = later
+	res.Status = "down"
+	changed := r.Reconciler.updateCheckStatus(&check, *res)
+
+	// Assert
+	r.t.Expect(changed).To(BeTrue())
+	ready := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionReady)
+	r.t.Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+	r.t.Expect(ready.Reason).To(Equal("Down"))
+	r.t.Expect(ready.LastTransitionTime).To(Equal(later))
+	synced := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionSynced)
+	r.t.Expect(synced.LastTransitionTime).To(Equal(serverTime), "status of synced condition did not change")
+
+	// Make sure no event is recorded before the status is stored
+	r.t.Expect(r.Events()).To(BeEmpty())
+}
+
+func TestCheckController_StatusChangedEvent(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	_, message := statusChangedEvent("", healthchecksio.HealthcheckResponse{Status: "new"})
+	g.Expect(message).To(BeEmpty())
+	_, message = statusChangedEvent("up", healthchecksio.HealthcheckResponse{Status: "up"})
+	g.Expect(message).To(BeEmpty())
+	eventType, message := statusChangedEvent("up", healthchecksio.HealthcheckResponse{Status: "down"})
+	g.Expect(eventType).To(Equal(corev1.EventTypeWarning))
+	g.Expect(message).To(Equal("Healthcheck status changed from up to down"))
+	eventType, _ = statusChangedEvent("down", healthchecksio.HealthcheckResponse{Status: "up"})
+	g.Expect(eventType).To(Equal(corev1.EventTypeNormal))
+}
+
 func TestCheckController_CreateCheck(t *testing.T) {
 	var (
 		name        = "example"
@@ -433,27 +901,532 @@ func TestCheckController_CreateCheck(t *testing.T) {
 
 	// Make sure an ID is set, or else the create probably failed silently
 	ctx.t.Expect(check.Status.ID).To(Equal("e71024f4-8537-4dd2-b742-ebe5a1685776"))
+
+	// Make sure conditions reflect the result of the sync
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionSynced)).To(BeTrue())
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionReady)).To(BeTrue())
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionChannelsResolved)).To(BeTrue())
+
+	// Make sure the creation was recorded as an event
+	ctx.t.Expect(ctx.Events()).To(ConsistOf("Normal Created Created healthcheck e71024f4-8537-4dd2-b742-ebe5a1685776"))
 }
 
-func TestCheckController_DeleteCheck(t *testing.T) {
+func TestCheckController_SyncEvent(t *testing.T) {
+	g := NewGomegaWithT(t)
+	healthcheck := &healthchecksio.HealthcheckResponse{UpdateURL: "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776"}
+	id := healthcheck.ID()
+	created := monitoringv1alpha1.Check{}
+	adopted := monitoringv1alpha1.Check{Spec: monitoringv1alpha1.CheckSpec{AdoptID: id}}
+	synced := monitoringv1alpha1.Check{Spec: monitoringv1alpha1.CheckSpec{AdoptID: id}, Status: monitoringv1alpha1.CheckStatus{ID: id}}
+
+	reason, _ := syncEvent(created, healthcheck, controllerutil.OperationResultNone)
+	g.Expect(reason).To(BeEmpty())
+	reason, message := syncEvent(created, healthcheck, controllerutil.OperationResultCreated)
+	g.Expect(reason).To(Equal(EventReasonCreated))
+	g.Expect(message).To(Equal("Created healthcheck " + id))
+	reason, _ = syncEvent(created, healthcheck, controllerutil.OperationResultUpdated)
+	g.Expect(reason).To(Equal(EventReasonUpdated))
+	reason, _ = syncEvent(adopted, healthcheck, controllerutil.OperationResultUpdated)
+	g.Expect(reason).To(Equal(EventReasonAdopted))
+	reason, _ = syncEvent(synced, healthcheck, controllerutil.OperationResultUpdated)
+	g.Expect(reason).To(Equal(EventReasonUpdated))
+}
+
+func TestCheckController_CreateCheck_Poller(t *testing.T) {
 	var (
-		name      = "example"
-		namespace = "testnamespace"
-		now       = metav1.Now()
+		name      = "foo"
+		namespace = "default"
+		timeout   = int32(3600)
 	)
 
+	writes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.URL.Path == "/channels/":
+			res.Write([]byte(pollerChannelsResponse))
+		case req.Method == http.MethodPost:
+			writes++
+			res.Write([]byte(`{
+				"name": "default/foo",
+				"status": "new",
+				"update_url": "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776"
+			}`))
+		default:
+			res.Write([]byte(pollerChecksResponse))
+		}
+	}))
+	defer server.Close()
+
 	// Create a Reconciler test context
 	ctx := NewCheckReconcilerTest(
 		t,
+		WithHckioBaseURL(server.URL),
 		WithK8sObjects(&monitoringv1alpha1.Check{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:              name,
-				Namespace:         namespace,
-				DeletionTimestamp: &now,
-				Finalizers: []string{
-					finalizerName,
-				},
-			},
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: monitoringv1alpha1.CheckSpec{
+				Timeout:  &timeout,
+				Channels: []string{"email"},
+			},
+		}),
+	)
+	ctx.Reconciler.Poller = NewStatusPoller(testutil.LogrTestLogger{T: t}, time.Minute)
+	req := NewReconcileRequest(name, namespace)
+
+	// Act, the check is written once and its status is refreshed from the poller
+	_, err := ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.Reconciler.Poller.Poll()
+	_, err = ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Assert
+	ctx.t.Expect(writes).To(Equal(1))
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(check.Status.Status).To(Equal("up"))
+
+	// Act & assert, a changed spec is written
+	timeout = int32(7200)
+	check.Spec.Timeout = &timeout
+	ctx.t.Expect(ctx.Reconciler.Client.Update(context.TODO(), check)).To(Succeed())
+	_, err = ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(writes).To(Equal(2))
+}
+
+func TestCheckController_PollFailed(t *testing.T) {
+	var (
+		name      = "foo"
+		namespace = "default"
+		timeout   = int32(3600)
+	)
+
+	fakeHckio := testutil.NewFakeHealthchecks()
+	defer fakeHckio.Close()
+
+	// Create a Reconciler test context
+	now := metav1.Now()
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithHckioBaseURL(fakeHckio.URL),
+		WithReconcilerClock(func() *metav1.Time { return &now }),
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: monitoringv1alpha1.CheckSpec{
+				Timeout: &timeout,
+			},
+		}),
+	)
+	limiter := newTestAPILimiter(&now, 2)
+	hc := testutil.NewTestHealthchecksioClient(t, "api-key", fakeHckio.URL)
+	hc.HTTPClient = limiter.Client(hc.HTTPClient)
+	ctx.Reconciler.Hckio = NewHealthchecksioAPI(hc)
+	ctx.Reconciler.Limiter = limiter
+	ctx.Reconciler.Poller = NewStatusPoller(testutil.LogrTestLogger{T: t}, time.Minute)
+	ctx.Reconciler.Poller.Clock = ctx.Reconciler.Clock
+	req := NewReconcileRequest(name, namespace)
+
+	_, err := ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.Reconciler.Poller.Poll()
+	id := fakeHckio.Checks()[0].ID
+
+	// Act, polling fails and the check is no longer served from the state polled before
+	fakeHckio.FailRequests(http.StatusServiceUnavailable, 2)
+	ctx.Reconciler.Poller.Poll()
+	fakeHckio.ResetRequests()
+	result, err := ctx.Reconciler.Reconcile(req)
+
+	// Assert, the check is read and the API reported unavailable
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(result.RequeueAfter).To(Equal(time.Minute))
+	fakeHckio.AssertRequests(t, "GET /checks/"+id)
+
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionAPIUnavailable)).To(BeTrue())
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionSynced)).To(BeFalse())
+}
+
+func TestCheckController_Throttled(t *testing.T) {
+	var (
+		name      = "foo"
+		namespace = "default"
+		timeout   = int32(3600)
+	)
+
+	fakeHckio := testutil.NewFakeHealthchecks()
+	defer fakeHckio.Close()
+
+	// Create a Reconciler test context, the limiter has a single token
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithHckioBaseURL(fakeHckio.URL),
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: monitoringv1alpha1.CheckSpec{
+				Timeout: &timeout,
+			},
+		}),
+	)
+	limiter := NewAPILimiter(0.001, 1, 1, time.Minute)
+	limiter.MaxWait = 10 * time.Millisecond
+	hc := testutil.NewTestHealthchecksioClient(t, "api-key", fakeHckio.URL)
+	hc.HTTPClient = limiter.Client(hc.HTTPClient)
+	ctx.Reconciler.Hckio = NewHealthchecksioAPI(hc)
+	ctx.Reconciler.Limiter = limiter
+	req := NewReconcileRequest(name, namespace)
+
+	_, err := ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.Events()
+
+	// Act, the changed check waits for a token of the limiter
+	check := &monitoringv1alpha1.Check{}
+	ctx.t.Expect(ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)).To(Succeed())
+	timeout = int32(7200)
+	check.Spec.Timeout = &timeout
+	ctx.t.Expect(ctx.Reconciler.Client.Update(context.TODO(), check)).To(Succeed())
+	fakeHckio.ResetRequests()
+	result, err := ctx.Reconciler.Reconcile(req)
+
+	// Assert, the check is requeued once there is a token, without reporting a failure
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(result.RequeueAfter).To(BeNumerically(">", 10*time.Minute))
+	ctx.t.Expect(fakeHckio.Requests()).To(BeEmpty())
+	ctx.t.Expect(ctx.Events()).To(BeEmpty())
+
+	ctx.t.Expect(ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)).To(Succeed())
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(check.Status.Conditions, monitoringv1alpha1.ConditionSynced)).To(BeTrue())
+	ctx.t.Expect(monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionAPIUnavailable)).To(BeNil())
+}
+
+func TestCheckController_RefreshCheck(t *testing.T) {
+	var (
+		name      = "foo"
+		namespace = "default"
+		timeout   = int32(3600)
+	)
+
+	requests := make([]string, 0)
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Method == http.MethodGet && deleted {
+			res.WriteHeader(http.StatusNotFound)
+			res.Write([]byte(`{"error": "not found"}`))
+			return
+		}
+		status := "new"
+		if req.Method == http.MethodGet {
+			status = "up"
+		}
+		res.Write([]byte(`{
+			"name": "default/foo",
+			"status": "` + status + `",
+			"update_url": "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776"
+		}`))
+	}))
+	defer server.Close()
+
+	// Create a Reconciler test context
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithHckioBaseURL(server.URL),
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: monitoringv1alpha1.CheckSpec{
+				Timeout: &timeout,
+			},
+		}),
+	)
+	req := NewReconcileRequest(name, namespace)
+
+	// Act, the check is written once and its status is refreshed with a read
+	_, err := ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	_, err = ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Assert
+	ctx.t.Expect(requests).To(Equal([]string{"POST /checks/", "GET /checks/e71024f4-8537-4dd2-b742-ebe5a1685776"}))
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(check.Status.Status).To(Equal("up"))
+	ctx.t.Expect(check.Status.AppliedSpecHash).ToNot(BeEmpty())
+	events := ctx.Events()
+	ctx.t.Expect(events).To(ContainElement("Normal Created Created healthcheck e71024f4-8537-4dd2-b742-ebe5a1685776"))
+	ctx.t.Expect(events).ToNot(ContainElement(HavePrefix("Normal Updated")))
+
+	// Act & assert, a check deleted from healthchecks.io is created again
+	deleted = true
+	_, err = ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(requests[2:]).To(Equal([]string{"GET /checks/e71024f4-8537-4dd2-b742-ebe5a1685776", "POST /checks/"}))
+}
+
+func TestCheckController_FakeHealthchecks(t *testing.T) {
+	var (
+		name      = "foo"
+		namespace = "default"
+		timeout   = int32(3600)
+	)
+
+	fake := testutil.NewFakeHealthchecks()
+	defer fake.Close()
+	fake.AddChannel("Ops", "email")
+
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithHckioBaseURL(fake.URL),
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: monitoringv1alpha1.CheckSpec{
+				Timeout:  &timeout,
+				Channels: []string{"email"},
+			},
+		}),
+	)
+	req := NewReconcileRequest(name, namespace)
+
+	// Act, the check is created
+	_, err := ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Assert
+	hc := fake.AssertCheck(t, "default/foo")
+	ctx.t.Expect(hc.Timeout).To(Equal(3600))
+	ctx.t.Expect(hc.Channels).ToNot(BeEmpty())
+
+	// Act, the check goes down and its status is refreshed
+	_, err = http.Post(fake.PingURL(hc.ID)+"/fail", "text/plain", nil)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	_, err = ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Assert
+	fake.AssertRequests(t, "GET /channels/", "POST /checks/", "POST /ping/"+hc.ID+"/fail", "GET /channels/", "GET /checks/"+hc.ID)
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(check.Status.ID).To(Equal(hc.ID))
+	ctx.t.Expect(check.Status.Status).To(Equal("down"))
+	ctx.t.Expect(monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionReady).Status).To(Equal(metav1.ConditionFalse))
+}
+
+func TestCheckController_AdoptCheck(t *testing.T) {
+	var (
+		name      = "example"
+		namespace = "testnamespace"
+		adoptID   = "e71024f4-8537-4dd2-b742-ebe5a1685776"
+		requests  = make([]string, 0)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", req.Method, req.URL.Path, b))
+		res.Write([]byte(`{
+			"name": "testnamespace/example",
+			"ping_url": "https://hc-ping.com/e71024f4-8537-4dd2-b742-ebe5a1685776",
+			"status": "up",
+			"n_pings": 1337,
+			"update_url": "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776"
+		}`))
+	}))
+	defer server.Close()
+
+	// Create a Reconciler test context
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithHckioBaseURL(server.URL),
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Spec: monitoringv1alpha1.CheckSpec{
+				Schedule: "* * * * *",
+				AdoptID:  adoptID,
+			},
+		}),
+	)
+	req := NewReconcileRequest(name, namespace)
+
+	// Act
+	_, err := ctx.Reconciler.Reconcile(req)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred(), "expected no errors during reconcile")
+
+	// Make sure the existing check was updated rather than a new one created
+	ctx.t.Expect(requests).To(Equal([]string{
+		`POST /checks/` + adoptID + ` {"name":"testnamespace/example","schedule":"* * * * *"}`,
+	}))
+
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(check.Status.ID).To(Equal(adoptID))
+	ctx.t.Expect(*check.Status.Pings).To(Equal(int32(1337)))
+
+	// Make sure the adoption was recorded as an event
+	ctx.t.Expect(ctx.Events()).To(ConsistOf("Normal Adopted Adopted healthcheck " + adoptID))
+}
+
+func TestCheckController_CreateCheck_Error(t *testing.T) {
+	var (
+		name      = "example"
+		namespace = "testnamespace"
+	)
+
+	// Create a Reconciler test context
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		}),
+		WithHckioServerResponse(400, `{"error": "invalid json"}`),
+		WithHckioServerResponse(400, `{"error": "invalid json"}`),
+	)
+	req := NewReconcileRequest(name, namespace)
+
+	// Act
+	_, err := ctx.Reconciler.Reconcile(req)
+
+	// Make sure reconcile returns the error so that it is retried
+	ctx.t.Expect(err).To(HaveOccurred())
+
+	// Make sure the failure is reported on the Synced condition
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	synced := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionSynced)
+	ctx.t.Expect(synced).ToNot(BeNil())
+	ctx.t.Expect(synced.Status).To(Equal(metav1.ConditionFalse))
+	ctx.t.Expect(synced.Reason).To(Equal("CreateOrUpdateFailed"))
+	ctx.t.Expect(synced.Message).To(ContainSubstring("invalid json"))
+
+	// Make sure the failure was recorded as an event
+	ctx.t.Expect(ctx.Events()).To(ConsistOf(HavePrefix("Warning SyncFailed")))
+
+	// Make sure retrying the failed sync does not write the unchanged status again
+	counting := &statusUpdateCountingClient{Client: ctx.Reconciler.Client}
+	ctx.Reconciler.Client = counting
+	_, err = ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).To(HaveOccurred())
+	ctx.t.Expect(counting.updates).To(Equal(0))
+}
+
+func TestCheckController_CreateCheck_APIUnavailable(t *testing.T) {
+	var (
+		name      = "example"
+		namespace = "testnamespace"
+	)
+
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(status)
+		res.Write([]byte(`{
+			"name": "testnamespace/example",
+			"status": "new",
+			"update_url": "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776"
+		}`))
+	}))
+	defer server.Close()
+
+	now := metav1.Now()
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithHckioBaseURL(server.URL),
+		WithReconcilerClock(func() *metav1.Time { return &now }),
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+		}),
+	)
+	limiter := NewAPILimiter(1000, 1000, 1, 2*time.Minute)
+	limiter.Clock = ctx.Reconciler.Clock
+	ctx.Reconciler.Limiter = limiter
+	client := testutil.NewTestHealthchecksioClient(t, "api-key", server.URL)
+	client.HTTPClient = limiter.Client(client.HTTPClient)
+	ctx.Reconciler.Hckio = NewHealthchecksioAPI(client)
+	req := NewReconcileRequest(name, namespace)
+
+	// Act
+	result, err := ctx.Reconciler.Reconcile(req)
+
+	// Make sure reconcile backs off until the circuit closes instead of returning the error
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(result.RequeueAfter).To(Equal(2 * time.Minute))
+
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	synced := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionSynced)
+	ctx.t.Expect(synced).ToNot(BeNil())
+	ctx.t.Expect(synced.Status).To(Equal(metav1.ConditionFalse))
+	ctx.t.Expect(synced.Reason).To(Equal("ApiUnavailable"))
+
+	unavailable := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionAPIUnavailable)
+	ctx.t.Expect(unavailable).ToNot(BeNil())
+	ctx.t.Expect(unavailable.Status).To(Equal(metav1.ConditionTrue))
+
+	// Act & assert, the condition is removed once the api responds again
+	now = metav1.NewTime(now.Add(2 * time.Minute))
+	status = http.StatusOK
+	_, err = ctx.Reconciler.Reconcile(req)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionAPIUnavailable)).To(BeNil())
+	ctx.t.Expect(monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionSynced).Status).To(Equal(metav1.ConditionTrue))
+}
+
+func TestCheckController_DeleteCheck(t *testing.T) {
+	var (
+		name      = "example"
+		namespace = "testnamespace"
+		now       = metav1.Now()
+	)
+
+	// Create a Reconciler test context
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				DeletionTimestamp: &now,
+				Finalizers: []string{
+					finalizerName,
+				},
+			},
 		}),
 		WithHckioServerResponse(200, `{}`),
 	)
@@ -473,6 +1446,57 @@ func TestCheckController_DeleteCheck(t *testing.T) {
 
 	// Make sure finalizer was removed
 	ctx.t.Expect(len(check.ObjectMeta.Finalizers)).To(Equal(0))
+
+	// Make sure the deletion was recorded as an event
+	ctx.t.Expect(ctx.Events()).To(ConsistOf(HavePrefix("Normal Deleted")))
+}
+
+func TestCheckController_DeleteCheck_Retain(t *testing.T) {
+	var (
+		name      = "example"
+		namespace = "testnamespace"
+		now       = metav1.Now()
+	)
+
+	// Create a Reconciler test context
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				DeletionTimestamp: &now,
+				Finalizers: []string{
+					finalizerName,
+				},
+			},
+			Spec: monitoringv1alpha1.CheckSpec{
+				DeletionPolicy: monitoringv1alpha1.DeletionPolicyRetain,
+			},
+			Status: monitoringv1alpha1.CheckStatus{
+				ID: "c1",
+			},
+		}),
+		WithHckioServerResponse(200, `{}`),
+	)
+	ctx.Reconciler.OwnershipTag = "k8s-operator"
+	req := NewReconcileRequest(name, namespace)
+
+	// Act
+	res, err := ctx.Reconciler.Reconcile(req)
+
+	// Make sure reconcile had not errors and that we do not requeue
+	ctx.t.Expect(err).ToNot(HaveOccurred(), "expected no errors during reconcile")
+	ctx.t.Expect(res).To(Equal(reconcile.Result{}), "not expecting a requeue")
+
+	// Make sure finalizer was removed
+	check := &monitoringv1alpha1.Check{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(len(check.ObjectMeta.Finalizers)).To(Equal(0))
+
+	// Make sure the check was retained rather than deleted
+	ctx.t.Expect(ctx.Events()).To(ConsistOf(HavePrefix("Normal Retained")))
 }
 
 func GenerateRandomString(n int) string {
@@ -545,7 +1569,14 @@ func NewReconcileRequest(name, namespace string) reconcile.Request {
 func NewCheckReconcilerWithT(t *testing.T, o *TestOptions) *CheckReconciler {
 	// Register known types
 	s := scheme.Scheme
-	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{})
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion,
+		&monitoringv1alpha1.Check{},
+		&monitoringv1alpha1.CheckList{},
+		&monitoringv1alpha1.HealthchecksProject{},
+		&monitoringv1alpha1.HealthchecksProjectList{},
+		&monitoringv1alpha1.ClusterHealthchecksProject{},
+		&monitoringv1alpha1.ClusterHealthchecksProjectList{},
+	)
 
 	// Create a fake k8s client
 	kc := fake.NewFakeClient(o.K8sObjects...)
@@ -554,10 +1585,14 @@ func NewCheckReconcilerWithT(t *testing.T, o *TestOptions) *CheckReconciler {
 	hc := testutil.NewTestHealthchecksioClient(t, o.HckioAPIKey, o.HckioBaseURL)
 
 	return &CheckReconciler{
-		Client: kc,
-		Scheme: s,
-		Log:    testutil.LogrTestLogger{T: t},
-		Hckio:  hc,
+		Client:   kc,
+		Scheme:   s,
+		Log:      testutil.LogrTestLogger{T: t},
+		Recorder: record.NewFakeRecorder(100),
+		Hckio:    NewHealthchecksioAPI(hc),
+		Clients: NewClientCache(func(apiKey string) *healthchecksio.Client {
+			return testutil.NewTestHealthchecksioClient(t, apiKey, o.HckioBaseURL)
+		}),
 		Clock: Clock{
 			Source: o.ReconcilerClock,
 		},
@@ -575,6 +1610,20 @@ func (c *CheckReconcilerTestContext) Close() {
 	c.HealthchecksioServer.Close()
 }
 
+// Events returns the events recorded by the reconciler so far
+func (c *CheckReconcilerTestContext) Events() []string {
+	events := make([]string, 0)
+	recorder := c.Reconciler.Recorder.(*record.FakeRecorder)
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
 func NewCheckReconcilerTest(t *testing.T, opts ...Option) *CheckReconcilerTestContext {
 	// Setup default test options
 	st := time.Now()
@@ -609,3 +1658,64 @@ func NewCheckReconcilerTest(t *testing.T, opts ...Option) *CheckReconcilerTestCo
 		t:                    g,
 	}
 }
+
+// statusUpdateCountingClient counts the updates of the status of objects
+type statusUpdateCountingClient struct {
+	client.Client
+	updates int
+}
+
+func (c *statusUpdateCountingClient) Status() client.StatusWriter {
+	return c
+}
+
+func (c *statusUpdateCountingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	c.updates++
+	return c.Client.Status().Update(ctx, obj, opts...)
+}
+
+func (c *statusUpdateCountingClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.Client.Status().Patch(ctx, obj, patch, opts...)
+}
+
+// fieldIndexedClient filters the objects listed by the fields of its indexes, which the fake client ignores
+type fieldIndexedClient struct {
+	client.Client
+	indexes map[string]client.IndexerFunc
+}
+
+// List lists the objects matching the field selector of the options
+func (c *fieldIndexedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	listOpts := &client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if err := c.Client.List(ctx, list, opts...); err != nil || listOpts.FieldSelector == nil {
+		return err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	matching := make([]runtime.Object, 0)
+	for _, item := range items {
+		for _, r := range listOpts.FieldSelector.Requirements() {
+			index, ok := c.indexes[r.Field]
+			if ok && containsString(index(item), r.Value) {
+				matching = append(matching, item)
+			}
+		}
+	}
+	return meta.SetList(list, matching)
+}
+
+// recordingAPI records the checks deleted through a HealthchecksAPI
+type recordingAPI struct {
+	HealthchecksAPI
+	deleted []string
+}
+
+// Delete records the id and deletes the check
+func (a *recordingAPI) Delete(id string) (*healthchecksio.HealthcheckResponse, error) {
+	a.deleted = append(a.deleted, id)
+	return a.HealthchecksAPI.Delete(id)
+}
@@ -0,0 +1,299 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+const (
+	lifecycleTimeout           = 10 * time.Second
+	lifecycleReconcileInterval = 2 * time.Second
+	lifecycleCircuitOpen       = 2 * time.Second
+)
+
+var lifecycleNamespaces = 0
+
+// startCheckManager runs a manager with a CheckReconciler managing the Checks of namespace in fake,
+// until the returned function is called, which returns once the manager stopped
+func startCheckManager(fake *testutil.FakeHealthchecks, namespace string) func() {
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme.Scheme,
+		Namespace:          namespace,
+		MetricsBindAddress: "0",
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	clients := NewClientCache(func(apiKey string) *healthchecksio.Client {
+		client := healthchecksio.NewClient(apiKey)
+		client.BaseURL = fake.URL
+		return client
+	})
+	limiter := NewAPILimiter(100, 100, 3, lifecycleCircuitOpen)
+	clients.Limiter = limiter
+
+	hckio, err := clients.Get(fake.APIKey, ClientOptions{})
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&CheckReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		Log:               ctrl.Log.WithName("controllers").WithName("Check"),
+		Recorder:          mgr.GetEventRecorderFor("check-controller"),
+		Hckio:             NewHealthchecksioAPI(hckio),
+		Clients:           clients,
+		Clock:             NewClock(),
+		ReconcileInterval: lifecycleReconcileInterval,
+		Limiter:           limiter,
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer GinkgoRecover()
+		defer close(stopped)
+		Expect(mgr.Start(stop)).To(Succeed())
+	}()
+
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+var _ = Describe("Check lifecycle", func() {
+	var (
+		ctx       context.Context
+		fake      *testutil.FakeHealthchecks
+		namespace string
+		stop      func()
+	)
+
+	getCheck := func(name string) func() (*monitoringv1alpha1.Check, error) {
+		return func() (*monitoringv1alpha1.Check, error) {
+			check := &monitoringv1alpha1.Check{}
+			err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, check)
+			return check, err
+		}
+	}
+
+	condition := func(name, conditionType string) func() metav1.ConditionStatus {
+		return func() metav1.ConditionStatus {
+			check, err := getCheck(name)()
+			if err != nil {
+				return ""
+			}
+			if c := monitoringv1alpha1.FindCondition(check.Status.Conditions, conditionType); c != nil {
+				return c.Status
+			}
+			return ""
+		}
+	}
+
+	statusID := func(name string) func() string {
+		return func() string {
+			check, err := getCheck(name)()
+			if err != nil {
+				return ""
+			}
+			return check.Status.ID
+		}
+	}
+
+	requests := func() []string {
+		r := make([]string, 0)
+		for _, req := range fake.Requests() {
+			r = append(r, req.String())
+		}
+		return r
+	}
+
+	createCheck := func(name string, spec monitoringv1alpha1.CheckSpec) *monitoringv1alpha1.Check {
+		check := &monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       spec,
+		}
+		Expect(k8sClient.Create(ctx, check)).To(Succeed())
+		return check
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		fake = testutil.NewFakeHealthchecks()
+		fake.APIKey = "api-key"
+
+		// every spec runs in a namespace of its own, watched by a manager of its own
+		lifecycleNamespaces++
+		namespace = fmt.Sprintf("lifecycle-%d", lifecycleNamespaces)
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).To(Succeed())
+
+		stop = startCheckManager(fake, namespace)
+	})
+
+	AfterEach(func() {
+		stop()
+		fake.Close()
+	})
+
+	It("creates the check and refreshes its status", func() {
+		timeout := int32(300)
+		createCheck("create", monitoringv1alpha1.CheckSpec{Timeout: &timeout})
+
+		By("creating the check in healthchecks.io")
+		Eventually(statusID("create"), lifecycleTimeout).ShouldNot(BeEmpty())
+		hc, ok := fake.CheckByName(namespace + "/create")
+		Expect(ok).To(BeTrue())
+		Expect(hc.Timeout).To(Equal(300))
+
+		check, err := getCheck("create")()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(check.Status.ID).To(Equal(hc.ID))
+		Expect(check.Status.PingURL).To(Equal(fake.PingURL(hc.ID)))
+		Expect(check.Status.Status).To(Equal("new"))
+		Expect(check.Finalizers).To(ContainElement(finalizerName))
+		Expect(condition("create", monitoringv1alpha1.ConditionSynced)()).To(Equal(metav1.ConditionTrue))
+
+		By("refreshing the status when the check goes down")
+		_, err = http.Post(fake.PingURL(hc.ID)+"/fail", "text/plain", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(condition("create", monitoringv1alpha1.ConditionReady), lifecycleTimeout).Should(Equal(metav1.ConditionFalse))
+
+		By("only reading the check while its spec is unchanged")
+		posts := 0
+		for _, r := range requests() {
+			if r == "POST /checks/" {
+				posts++
+			}
+		}
+		Expect(posts).To(Equal(1))
+	})
+
+	It("updates the check when its spec changes", func() {
+		timeout := int32(300)
+		createCheck("update", monitoringv1alpha1.CheckSpec{Timeout: &timeout})
+		Eventually(statusID("update"), lifecycleTimeout).ShouldNot(BeEmpty())
+
+		// the status may be updated in between, retry on conflicts
+		var check *monitoringv1alpha1.Check
+		Eventually(func() error {
+			var err error
+			if check, err = getCheck("update")(); err != nil {
+				return err
+			}
+			timeout = int32(600)
+			check.Spec.Timeout = &timeout
+			check.Spec.Tags = []string{"prod"}
+			return k8sClient.Update(ctx, check)
+		}, lifecycleTimeout).Should(Succeed())
+
+		Eventually(func() int {
+			hc, _ := fake.CheckByName(namespace + "/update")
+			return hc.Timeout
+		}, lifecycleTimeout).Should(Equal(600))
+		hc, _ := fake.CheckByName(namespace + "/update")
+		Expect(hc.Tags).To(Equal("prod"))
+		Expect(fake.Checks()).To(HaveLen(1))
+
+		Eventually(func() int64 {
+			check, _ := getCheck("update")()
+			return check.Status.ObservedGeneration
+		}, lifecycleTimeout).Should(Equal(check.Generation))
+	})
+
+	It("resolves channels", func() {
+		email := fake.AddChannel("Ops", "email")
+		createCheck("channels", monitoringv1alpha1.CheckSpec{Channels: []string{"email/Ops", "slack"}})
+
+		Eventually(condition("channels", monitoringv1alpha1.ConditionChannelsResolved), lifecycleTimeout).Should(Equal(metav1.ConditionFalse))
+		hc, ok := fake.CheckByName(namespace + "/channels")
+		Expect(ok).To(BeTrue())
+		Expect(hc.Channels).To(Equal(email))
+
+		check, err := getCheck("channels")()
+		Expect(err).ToNot(HaveOccurred())
+		resolved := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionChannelsResolved)
+		Expect(resolved.Reason).To(Equal("ChannelsNotFound"))
+		Expect(resolved.Message).To(ContainSubstring("slack"))
+
+		By("resolving the channel once it exists")
+		fake.AddChannel("Alerts", "slack")
+		Eventually(condition("channels", monitoringv1alpha1.ConditionChannelsResolved), lifecycleTimeout).Should(Equal(metav1.ConditionTrue))
+	})
+
+	It("deletes the check and removes the finalizer", func() {
+		check := createCheck("delete", monitoringv1alpha1.CheckSpec{})
+		Eventually(statusID("delete"), lifecycleTimeout).ShouldNot(BeEmpty())
+		id := statusID("delete")()
+
+		Expect(k8sClient.Delete(ctx, check)).To(Succeed())
+		Eventually(func() bool {
+			_, err := getCheck("delete")()
+			return apierrs.IsNotFound(err)
+		}, lifecycleTimeout).Should(BeTrue())
+
+		_, ok := fake.Check(id)
+		Expect(ok).To(BeFalse())
+		Expect(requests()).To(ContainElement("DELETE /checks/" + id))
+	})
+
+	It("backs off while the api fails and retries once it recovers", func() {
+		fake.FailRequests(http.StatusServiceUnavailable, 3)
+		createCheck("retry", monitoringv1alpha1.CheckSpec{})
+
+		By("opening the circuit after repeated failures")
+		Eventually(condition("retry", monitoringv1alpha1.ConditionAPIUnavailable), lifecycleTimeout).Should(Equal(metav1.ConditionTrue))
+		Expect(condition("retry", monitoringv1alpha1.ConditionSynced)()).To(Equal(metav1.ConditionFalse))
+
+		By("creating the check once the circuit closes")
+		Eventually(condition("retry", monitoringv1alpha1.ConditionSynced), lifecycleTimeout).Should(Equal(metav1.ConditionTrue))
+		Expect(condition("retry", monitoringv1alpha1.ConditionAPIUnavailable)()).To(BeEmpty())
+		Expect(fake.Checks()).To(HaveLen(1))
+	})
+
+	It("does not write unchanged checks after a restart", func() {
+		createCheck("restart", monitoringv1alpha1.CheckSpec{})
+		Eventually(statusID("restart"), lifecycleTimeout).ShouldNot(BeEmpty())
+		id := statusID("restart")()
+
+		// the first manager must not reconcile along with the second one
+		stop()
+		fake.ResetRequests()
+		stop = startCheckManager(fake, namespace)
+
+		Eventually(requests, lifecycleTimeout).Should(ContainElement("GET /checks/" + id))
+		Consistently(requests, 2*lifecycleReconcileInterval).ShouldNot(ContainElement("POST /checks/"))
+		Expect(fake.Checks()).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,428 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// checkProject holds the client and settings used to manage a check
+type checkProject struct {
+	client          HealthchecksAPI
+	namePrefix      string
+	ownershipTag    string
+	defaultTags     []string
+	defaultChannels []string
+}
+
+// applyDefaults returns a copy of the check with the project defaults applied
+func (p checkProject) applyDefaults(check monitoringv1alpha1.Check) monitoringv1alpha1.Check {
+	c := *check.DeepCopy()
+
+	for _, tag := range p.defaultTags {
+		if !containsString(c.Spec.Tags, tag) {
+			c.Spec.Tags = append(c.Spec.Tags, tag)
+		}
+	}
+
+	if len(c.Spec.Channels) == 0 && len(p.defaultChannels) > 0 {
+		c.Spec.Channels = append([]string{}, p.defaultChannels...)
+	}
+
+	return c
+}
+
+// upsert creates or updates the check in healthchecks.io. A check adopting an
+// existing check updates it by its UUID rather than matching it by name. The
+// check is reported as created when it got another UUID than the one in its status.
+func (p checkProject) upsert(check monitoringv1alpha1.Check, channels ...string) (*healthchecksio.HealthcheckResponse, controllerutil.OperationResult, error) {
+	healthcheck := p.convertToHealthcheck(check, channels...)
+
+	if check.Spec.AdoptID != "" {
+		healthcheck.Unique = nil
+		hc, err := p.client.Update(check.Spec.AdoptID, healthcheck)
+		if err != nil {
+			return nil, controllerutil.OperationResultNone, err
+		}
+		return hc, controllerutil.OperationResultUpdated, nil
+	}
+
+	hc, err := p.client.Create(healthcheck)
+	if err != nil {
+		return nil, controllerutil.OperationResultNone, err
+	}
+	if hc.ID() == check.Status.ID {
+		return hc, controllerutil.OperationResultUpdated, nil
+	}
+	return hc, controllerutil.OperationResultCreated, nil
+}
+
+// defaultProject returns the project configured through the operator settings
+func (r *CheckReconciler) defaultProject() checkProject {
+	return checkProject{
+		client:       r.Hckio,
+		namePrefix:   r.NamePrefix,
+		ownershipTag: r.OwnershipTag,
+	}
+}
+
+// resolveProject returns the project used to manage the check
+func (r *CheckReconciler) resolveProject(ctx context.Context, check *monitoringv1alpha1.Check) (checkProject, error) {
+	return projectResolver{
+		client:   r.Client,
+		secrets:  uncachedReader(r.Client, r.APIReader),
+		clients:  r.Clients,
+		newAPI:   r.NewAPI,
+		defaults: r.defaultProject(),
+	}.resolve(ctx, check)
+}
+
+// projectResolver resolves the projects used to manage checks
+type projectResolver struct {
+	client   client.Reader
+	secrets  client.Reader
+	clients  *ClientCache
+	newAPI   APIFactory
+	defaults checkProject
+}
+
+// resolve returns the project used to manage the check
+func (pr projectResolver) resolve(ctx context.Context, check *monitoringv1alpha1.Check) (checkProject, error) {
+	spec, namespace, namespaced, err := checkProjectSpec(ctx, pr.client, check)
+	if err != nil {
+		return pr.defaults, err
+	}
+
+	if spec != nil {
+		return pr.fromSpec(ctx, *spec, namespace, namespaced)
+	}
+
+	project := pr.defaults
+	if ref := check.Spec.APIKeySecretRef; ref != nil {
+		apiKey, err := readSecretKey(ctx, pr.secrets, check.Namespace, *ref)
+		if err != nil {
+			return project, err
+		}
+		client, err := pr.clients.Get(apiKey, ClientOptions{})
+		if err != nil {
+			return project, err
+		}
+		project.client = newAPI(pr.newAPI, client, ClientOptions{})
+	}
+
+	return project, nil
+}
+
+// fromSpec returns the project of a HealthchecksProject or ClusterHealthchecksProject, reading
+// the API key from a Secret in namespace
+func (pr projectResolver) fromSpec(ctx context.Context, spec monitoringv1alpha1.HealthchecksProjectSpec, namespace string, namespaced bool) (checkProject, error) {
+	project := pr.defaults
+
+	client, err := projectClient(ctx, pr.secrets, pr.clients, pr.newAPI, spec, namespace, namespaced)
+	if err != nil {
+		return project, err
+	}
+
+	project.client = client
+	if spec.NamePrefix != "" {
+		project.namePrefix = spec.NamePrefix
+	}
+	project.defaultTags = spec.DefaultTags
+	project.defaultChannels = spec.DefaultChannels
+	return project, nil
+}
+
+// checkProjectSpec returns the spec of the project referenced by the check, the namespace of
+// its API key Secret and whether it is a namespaced HealthchecksProject, or nil when the check
+// does not reference a project
+func checkProjectSpec(ctx context.Context, c client.Reader, check *monitoringv1alpha1.Check) (*monitoringv1alpha1.HealthchecksProjectSpec, string, bool, error) {
+	ref := check.Spec.ProjectRef
+	if ref == nil {
+		return nil, "", false, nil
+	}
+
+	switch ref.Kind {
+	case "", "HealthchecksProject":
+		var hp monitoringv1alpha1.HealthchecksProject
+		if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: check.Namespace}, &hp); err != nil {
+			return nil, "", false, err
+		}
+		return &hp.Spec, hp.Namespace, true, nil
+	case "ClusterHealthchecksProject":
+		var chp monitoringv1alpha1.ClusterHealthchecksProject
+		if err := c.Get(ctx, types.NamespacedName{Name: ref.Name}, &chp); err != nil {
+			return nil, "", false, err
+		}
+		return &chp.Spec, chp.Spec.APIKeySecretRef.Namespace, false, nil
+	default:
+		return nil, "", false, fmt.Errorf("unsupported project kind %s", ref.Kind)
+	}
+}
+
+// pingOptions returns the options used to ping the check, reaching the instance of its project
+func pingOptions(ctx context.Context, c client.Reader, check *monitoringv1alpha1.Check) (ClientOptions, error) {
+	spec, _, _, err := checkProjectSpec(ctx, c, check)
+	if err != nil || spec == nil {
+		return ClientOptions{}, err
+	}
+
+	return ClientOptions{
+		BaseURL:            spec.BaseURL,
+		CABundle:           spec.CABundle,
+		InsecureSkipVerify: spec.InsecureSkipVerify,
+	}, nil
+}
+
+// ProjectPolicy restricts the settings of namespaced HealthchecksProjects. Their API key is sent to
+// their base URL, which anyone allowed to create a project in a namespace sets.
+type ProjectPolicy struct {
+	// AllowedBaseURLs are the base URLs a HealthchecksProject may set, besides the one of the operator
+	AllowedBaseURLs []string
+
+	// AllowInsecureSkipVerify allows a HealthchecksProject to skip verification of the certificate of its API
+	AllowInsecureSkipVerify bool
+}
+
+// NewProjectPolicy creates a ProjectPolicy allowing the comma separated allowedBaseURLs
+func NewProjectPolicy(allowedBaseURLs string) ProjectPolicy {
+	var policy ProjectPolicy
+	for _, u := range strings.Split(allowedBaseURLs, ",") {
+		if u = strings.TrimSuffix(strings.TrimSpace(u), "/"); u != "" {
+			policy.AllowedBaseURLs = append(policy.AllowedBaseURLs, u)
+		}
+	}
+	return policy
+}
+
+// validate returns an error when the spec of a HealthchecksProject is not allowed by the policy
+func (p ProjectPolicy) validate(spec monitoringv1alpha1.HealthchecksProjectSpec) error {
+	if spec.BaseURL != "" && !containsString(p.AllowedBaseURLs, strings.TrimSuffix(spec.BaseURL, "/")) {
+		return fmt.Errorf("the base url %s is not allowed for a HealthchecksProject", spec.BaseURL)
+	}
+	if spec.InsecureSkipVerify && !p.AllowInsecureSkipVerify {
+		return fmt.Errorf("insecureSkipVerify is not allowed for a HealthchecksProject")
+	}
+	return nil
+}
+
+// projectClient returns the healthchecksio API of a project created by factory, reading the API key from a Secret in namespace.
+// The spec of a namespaced HealthchecksProject has to be allowed by the ProjectPolicy of clients.
+func projectClient(ctx context.Context, c client.Reader, clients *ClientCache, factory APIFactory, spec monitoringv1alpha1.HealthchecksProjectSpec, namespace string, namespaced bool) (HealthchecksAPI, error) {
+	if namespace == "" {
+		return nil, fmt.Errorf("the namespace of the api key secret %s must be set", spec.APIKeySecretRef.Name)
+	}
+
+	if namespaced {
+		if err := clients.ProjectPolicy.validate(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	apiKey, err := readSecretKey(ctx, c, namespace, spec.APIKeySecretRef.SecretKeyReference)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := ClientOptions{
+		BaseURL:            spec.BaseURL,
+		CABundle:           spec.CABundle,
+		InsecureSkipVerify: spec.InsecureSkipVerify,
+	}
+	hckio, err := clients.Get(apiKey, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAPI(factory, hckio, opts), nil
+}
+
+// uncachedReader returns apiReader when it is set, so objects such as Secrets are read from the API
+// server instead of having the manager cache every one of them in the cluster, and c otherwise
+func uncachedReader(c client.Reader, apiReader client.Reader) client.Reader {
+	if apiReader == nil {
+		return c
+	}
+	return apiReader
+}
+
+// readSecretKey returns the value of the referenced key of a Secret
+func readSecretKey(ctx context.Context, c client.Reader, namespace string, ref monitoringv1alpha1.SecretKeyReference) (string, error) {
+	var secret corev1.Secret
+	if err := c.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, &secret); err != nil {
+		return "", err
+	}
+
+	value := string(secret.Data[ref.Key])
+	if value == "" {
+		return "", fmt.Errorf("key %s not found in secret %s/%s", ref.Key, namespace, ref.Name)
+	}
+
+	return value, nil
+}
+
+// Field indexes of the projects and API key Secrets referenced by Checks and projects
+const (
+	ProjectRefField      = ".spec.projectRef"
+	APIKeySecretRefField = ".spec.apiKeySecretRef"
+)
+
+// IndexProjectRef indexes a Check by the kind, namespace and name of the project it references
+func IndexProjectRef(obj runtime.Object) []string {
+	check, ok := obj.(*monitoringv1alpha1.Check)
+	if !ok || check.Spec.ProjectRef == nil {
+		return nil
+	}
+
+	ref := check.Spec.ProjectRef
+	if ref.Kind == "ClusterHealthchecksProject" {
+		return []string{projectRefKey(ref.Kind, "", ref.Name)}
+	}
+	return []string{projectRefKey("HealthchecksProject", check.Namespace, ref.Name)}
+}
+
+// IndexAPIKeySecretRef indexes a Check, HealthchecksProject or ClusterHealthchecksProject by the
+// namespace and name of the API key Secret it references
+func IndexAPIKeySecretRef(obj runtime.Object) []string {
+	switch o := obj.(type) {
+	case *monitoringv1alpha1.Check:
+		if o.Spec.APIKeySecretRef == nil {
+			return nil
+		}
+		return []string{o.Namespace + "/" + o.Spec.APIKeySecretRef.Name}
+	case *monitoringv1alpha1.HealthchecksProject:
+		return []string{o.Namespace + "/" + o.Spec.APIKeySecretRef.Name}
+	case *monitoringv1alpha1.ClusterHealthchecksProject:
+		return []string{o.Spec.APIKeySecretRef.Namespace + "/" + o.Spec.APIKeySecretRef.Name}
+	}
+	return nil
+}
+
+// projectRefKey returns the key a Check referencing the project is indexed by
+func projectRefKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// projectChecks maps a HealthchecksProject or ClusterHealthchecksProject to the Checks referencing it
+func (r *CheckReconciler) projectChecks(obj handler.MapObject) []reconcile.Request {
+	key := projectRefKey("HealthchecksProject", obj.Meta.GetNamespace(), obj.Meta.GetName())
+	if _, ok := obj.Object.(*monitoringv1alpha1.ClusterHealthchecksProject); ok {
+		key = projectRefKey("ClusterHealthchecksProject", "", obj.Meta.GetName())
+	}
+
+	return r.referencingChecks(ProjectRefField, key)
+}
+
+// secretChecks maps an API key Secret to the Checks referencing it, directly or through a project
+func (r *CheckReconciler) secretChecks(obj handler.MapObject) []reconcile.Request {
+	ctx := context.Background()
+	key := obj.Meta.GetNamespace() + "/" + obj.Meta.GetName()
+	requests := r.referencingChecks(APIKeySecretRefField, key)
+
+	var projects monitoringv1alpha1.HealthchecksProjectList
+	if err := r.List(ctx, &projects, client.MatchingFields{APIKeySecretRefField: key}); err != nil {
+		r.Log.Error(err, "unable to list HealthchecksProjects referencing Secret", "secret", key)
+	}
+	for _, hp := range projects.Items {
+		requests = append(requests, r.referencingChecks(ProjectRefField, projectRefKey("HealthchecksProject", hp.Namespace, hp.Name))...)
+	}
+
+	var clusterProjects monitoringv1alpha1.ClusterHealthchecksProjectList
+	if err := r.List(ctx, &clusterProjects, client.MatchingFields{APIKeySecretRefField: key}); err != nil {
+		r.Log.Error(err, "unable to list ClusterHealthchecksProjects referencing Secret", "secret", key)
+	}
+	for _, chp := range clusterProjects.Items {
+		requests = append(requests, r.referencingChecks(ProjectRefField, projectRefKey("ClusterHealthchecksProject", "", chp.Name))...)
+	}
+
+	return requests
+}
+
+// referencingChecks returns the requests of the Checks indexed by the field with the value
+func (r *CheckReconciler) referencingChecks(field, value string) []reconcile.Request {
+	var checks monitoringv1alpha1.CheckList
+	if err := r.List(context.Background(), &checks, client.MatchingFields{field: value}); err != nil {
+		r.Log.Error(err, "unable to list Checks by field index", "field", field, "value", value)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(checks.Items))
+	for _, check := range checks.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: check.Name, Namespace: check.Namespace}})
+	}
+	return requests
+}
+
+// LabelAPIKeySecret opts a Secret holding an API key in to being watched, so the Checks and projects
+// using it are synced again when it changes
+const LabelAPIKeySecret = "healthchecks.io/api-key-secret"
+
+// apiKeySecretInformer returns an informer of the Secrets labeled as API key Secret, which drops the data
+// of the Secrets before they are stored. Only their names are needed to map them to the Checks using them.
+func apiKeySecretInformer(clientset kubernetes.Interface) toolscache.SharedIndexInformer {
+	selector := func(opts *metav1.ListOptions) {
+		opts.LabelSelector = LabelAPIKeySecret + "=true"
+	}
+
+	return toolscache.NewSharedIndexInformer(&toolscache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			selector(&opts)
+			list, err := clientset.CoreV1().Secrets(metav1.NamespaceAll).List(opts)
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				withoutSecretData(&list.Items[i])
+			}
+			return list, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			selector(&opts)
+			w, err := clientset.CoreV1().Secrets(metav1.NamespaceAll).Watch(opts)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(e watch.Event) (watch.Event, bool) {
+				if secret, ok := e.Object.(*corev1.Secret); ok {
+					withoutSecretData(secret)
+				}
+				return e, true
+			}), nil
+		},
+	}, &corev1.Secret{}, 0, toolscache.Indexers{})
+}
+
+// withoutSecretData removes the data of the secret
+func withoutSecretData(secret *corev1.Secret) {
+	secret.Data = nil
+	secret.StringData = nil
+}
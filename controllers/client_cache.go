@@ -0,0 +1,154 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+)
+
+// ClientFactory creates a healthchecks.io client for an API key
+type ClientFactory func(apiKey string) *healthchecksio.Client
+
+// ClientOptions overrides the settings of the clients created by a ClientFactory
+type ClientOptions struct {
+	// The base URL of the healthchecks.io API
+	BaseURL string
+
+	// PEM encoded CA certificates used to verify the healthchecks.io API
+	CABundle []byte
+
+	// Skip verification of the certificate of the healthchecks.io API
+	InsecureSkipVerify bool
+}
+
+func (o ClientOptions) key() string {
+	return fmt.Sprintf("%s|%x|%t", o.BaseURL, sha256.Sum256(o.CABundle), o.InsecureSkipVerify)
+}
+
+// clientCacheExpiry is how long a client is cached for after it was last used
+const clientCacheExpiry = 1 * time.Hour
+
+// ClientCache holds one healthchecks.io client per API key and options
+type ClientCache struct {
+	// Limiter throttles the requests of every client returned by the cache
+	Limiter *APILimiter
+
+	// Instrument records the latency and errors of the requests of every client in the API metrics
+	Instrument bool
+
+	// ProjectPolicy restricts the settings of the HealthchecksProjects clients are created for
+	ProjectPolicy ProjectPolicy
+
+	// Expiry is how long a client is cached for after it was last used, so clients of
+	// rotated API keys and deleted projects are released
+	Expiry time.Duration
+
+	Clock Clock
+
+	factory ClientFactory
+	clients map[string]*cachedClient
+	mu      sync.Mutex
+}
+
+// cachedClient is a client held by a ClientCache
+type cachedClient struct {
+	client   *healthchecksio.Client
+	lastUsed time.Time
+}
+
+// NewClientCache creates a new ClientCache using factory to create clients
+func NewClientCache(factory ClientFactory) *ClientCache {
+	return &ClientCache{
+		Expiry:  clientCacheExpiry,
+		Clock:   NewClock(),
+		factory: factory,
+		clients: make(map[string]*cachedClient),
+	}
+}
+
+// Get returns the client for the API key and options, creating it when it does not exist.
+// Options left empty keep the settings of the factory. Clients unused for longer than Expiry are released.
+func (c *ClientCache) Get(apiKey string, opts ClientOptions) (*healthchecksio.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.Clock.Now().Time
+	for key, cached := range c.clients {
+		if now.Sub(cached.lastUsed) > c.Expiry {
+			delete(c.clients, key)
+		}
+	}
+
+	key := opts.key() + "|" + apiKey
+	if cached, ok := c.clients[key]; ok {
+		cached.lastUsed = now
+		return cached.client, nil
+	}
+
+	client := c.factory(apiKey)
+	if opts.BaseURL != "" {
+		client.BaseURL = opts.BaseURL
+	}
+	if len(opts.CABundle) > 0 || opts.InsecureSkipVerify {
+		httpClient, err := NewHTTPClient(opts.CABundle, opts.InsecureSkipVerify)
+		if err != nil {
+			return nil, err
+		}
+		// keep the timeout of the factory, requests must never hang
+		httpClient.Timeout = client.HTTPClient.Timeout
+		client.HTTPClient = httpClient
+	}
+	if c.Instrument {
+		client.HTTPClient = InstrumentClient(client.HTTPClient)
+	}
+	if c.Limiter != nil {
+		client.HTTPClient = c.Limiter.Client(client.HTTPClient)
+	}
+
+	c.clients[key] = &cachedClient{client: client, lastUsed: now}
+	return client, nil
+}
+
+// NewHTTPClient creates a http client trusting the CA certificates of caBundle in addition to the system roots
+func NewHTTPClient(caBundle []byte, insecureSkipVerify bool) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if len(caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no valid certificates found in ca bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+)
+
+func TestClientCache_Get(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	created := 0
+	cache := NewClientCache(func(apiKey string) *healthchecksio.Client {
+		created++
+		return healthchecksio.NewClient(apiKey)
+	})
+
+	selfHosted := ClientOptions{BaseURL: "https://hc.example.com/api/v1"}
+
+	// Act
+	foo, _ := cache.Get("foo", ClientOptions{})
+	bar, _ := cache.Get("bar", ClientOptions{})
+	baz, _ := cache.Get("foo", selfHosted)
+	insecure, _ := cache.Get("foo", ClientOptions{BaseURL: selfHosted.BaseURL, InsecureSkipVerify: true})
+
+	// Assert
+	g.Expect(foo.APIKey).To(Equal("foo"))
+	g.Expect(bar.APIKey).To(Equal("bar"))
+	g.Expect(baz.APIKey).To(Equal("foo"))
+	g.Expect(baz.BaseURL).To(Equal("https://hc.example.com/api/v1"))
+	g.Expect(insecure).ToNot(BeIdenticalTo(baz))
+	g.Expect(insecure.HTTPClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify).To(BeTrue())
+	g.Expect(cache.Get("foo", ClientOptions{})).To(BeIdenticalTo(foo))
+	g.Expect(cache.Get("foo", selfHosted)).To(BeIdenticalTo(baz))
+	g.Expect(created).To(Equal(4))
+}
+
+func TestClientCache_Get_Expiry(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	now := &metav1.Time{Time: time.Now()}
+	cache := NewClientCache(healthchecksio.NewClient)
+	cache.Clock = Clock{Source: func() *metav1.Time { return now }}
+
+	foo, _ := cache.Get("foo", ClientOptions{})
+	rotated, _ := cache.Get("rotated", ClientOptions{})
+
+	// Act
+	now = &metav1.Time{Time: now.Add(cache.Expiry)}
+	g.Expect(cache.Get("foo", ClientOptions{})).To(BeIdenticalTo(foo))
+	now = &metav1.Time{Time: now.Add(time.Minute)}
+
+	// Assert
+	g.Expect(cache.Get("foo", ClientOptions{})).To(BeIdenticalTo(foo))
+	g.Expect(cache.clients).To(HaveLen(1))
+	g.Expect(cache.Get("rotated", ClientOptions{})).ToNot(BeIdenticalTo(rotated))
+}
+
+func TestClientCache_Get_InvalidCABundle(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	cache := NewClientCache(healthchecksio.NewClient)
+
+	// Act
+	_, err := cache.Get("foo", ClientOptions{CABundle: []byte("not a certificate")})
+
+	// Assert
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestClientCache_Get_KeepsTimeout(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	cache := NewClientCache(func(apiKey string) *healthchecksio.Client {
+		client := healthchecksio.NewClient(apiKey)
+		client.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+		return client
+	})
+
+	// Act
+	client, err := cache.Get("foo", ClientOptions{InsecureSkipVerify: true})
+
+	// Assert
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(client.HTTPClient.Timeout).To(Equal(30 * time.Second))
+}
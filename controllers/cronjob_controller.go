@@ -0,0 +1,269 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// Annotations read from a CronJob monitored by the operator
+const (
+	// AnnotationMonitor enables monitoring of a CronJob when set to "true"
+	AnnotationMonitor = "healthchecks.io/monitor"
+
+	// AnnotationTimezone sets the timezone of the schedule, batch/v1beta1 CronJobs do not have a timezone field
+	AnnotationTimezone = "healthchecks.io/timezone"
+
+	// AnnotationGracePeriod sets the grace period of the check in seconds
+	AnnotationGracePeriod = "healthchecks.io/grace-period"
+
+	// AnnotationTags sets a comma separated list of tags for the check
+	AnnotationTags = "healthchecks.io/tags"
+
+	// AnnotationChannels sets a comma separated list of channels for the check
+	AnnotationChannels = "healthchecks.io/channels"
+)
+
+// Reasons used for events recorded on a CronJob
+const (
+	EventReasonInvalidAnnotations = "InvalidAnnotations"
+	EventReasonCheckRejected      = "CheckRejected"
+	EventReasonCheckConflict      = "CheckConflict"
+	EventReasonCheckCreated       = "CheckCreated"
+	EventReasonCheckUpdated       = "CheckUpdated"
+	EventReasonCheckDeleted       = "CheckDeleted"
+)
+
+// Bounds of the spec of a Check, as validated by its CRD
+const (
+	minGracePeriod    = 60
+	maxGracePeriod    = 2592000
+	maxCheckListItems = 100
+)
+
+// scheduleDescriptors maps the predefined schedules of CronJobs to the five field cron format
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// CronJobReconciler creates a Check for every CronJob annotated for monitoring
+type CronJobReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Log      logr.Logger
+	Recorder record.EventRecorder
+}
+
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch
+
+// Reconcile tries to reconcile the object
+func (r *CronJobReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("cronjob", req.NamespacedName)
+
+	var cronJob batchv1beta1.CronJob
+	if err := r.Get(ctx, req.NamespacedName, &cronJob); err != nil {
+		log.V(0).Info("unable to fetch CronJob from k8s")
+		return ctrl.Result{}, ignoreNotFound(err)
+	}
+	log.V(1).Info("fetched CronJob from k8s")
+
+	if !cronJob.ObjectMeta.DeletionTimestamp.IsZero() {
+		// the owned Check is deleted by the garbage collector of kubernetes
+		return ctrl.Result{}, nil
+	}
+
+	var existing monitoringv1alpha1.Check
+	err := r.Get(ctx, req.NamespacedName, &existing)
+	if err != nil && !apierrs.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+
+	if found && !metav1.IsControlledBy(&existing, &cronJob) {
+		if isMonitored(cronJob) {
+			log.V(0).Info("a Check with the name of the CronJob exists and is not owned by it")
+			r.Recorder.Eventf(&cronJob, corev1.EventTypeWarning, EventReasonCheckConflict, "Check %s already exists and is not owned by the CronJob", existing.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !isMonitored(cronJob) {
+		if found {
+			if err := r.Delete(ctx, &existing); err != nil {
+				return ctrl.Result{}, ignoreNotFound(err)
+			}
+			log.V(0).Info("deleted Check of CronJob no longer monitored")
+			r.Recorder.Eventf(&cronJob, corev1.EventTypeNormal, EventReasonCheckDeleted, "Deleted Check %s, the CronJob is no longer monitored", existing.Name)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	spec, err := checkSpecFromCronJob(cronJob)
+	if err != nil {
+		log.V(0).Info(fmt.Sprintf("invalid annotations: %s", err))
+		r.Recorder.Eventf(&cronJob, corev1.EventTypeWarning, EventReasonInvalidAnnotations, "Unable to create Check: %s", err)
+		// the CronJob has to be changed for this to succeed, no need to retry
+		return ctrl.Result{}, nil
+	}
+
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cronJob.Name,
+			Namespace: cronJob.Namespace,
+		},
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, check, func() error {
+		// keep the fields not managed from the CronJob, e.g. a pingURLTarget added by hand
+		check.Spec.Schedule = spec.Schedule
+		check.Spec.Timezone = spec.Timezone
+		check.Spec.GracePeriod = spec.GracePeriod
+		check.Spec.Tags = spec.Tags
+		check.Spec.Channels = spec.Channels
+		return controllerutil.SetControllerReference(&cronJob, check, r.Scheme)
+	})
+	if apierrs.IsInvalid(err) || apierrs.IsForbidden(err) {
+		log.V(0).Info(fmt.Sprintf("Check rejected: %s", err))
+		r.Recorder.Eventf(&cronJob, corev1.EventTypeWarning, EventReasonCheckRejected, "Unable to create or update Check: %s", err)
+		// rejected by the Check webhook or the API server, the CronJob or the Check has to be changed
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		log.Error(err, "unable to create or update Check for CronJob")
+		return ctrl.Result{}, err
+	}
+	switch op {
+	case controllerutil.OperationResultCreated:
+		log.V(0).Info("created Check for CronJob")
+		r.Recorder.Eventf(&cronJob, corev1.EventTypeNormal, EventReasonCheckCreated, "Created Check %s", check.Name)
+	case controllerutil.OperationResultUpdated:
+		log.V(0).Info("updated Check for CronJob")
+		r.Recorder.Eventf(&cronJob, corev1.EventTypeNormal, EventReasonCheckUpdated, "Updated Check %s", check.Name)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// isMonitored returns true when the CronJob is annotated for monitoring
+func isMonitored(cronJob batchv1beta1.CronJob) bool {
+	return cronJob.Annotations[AnnotationMonitor] == "true"
+}
+
+// checkSpecFromCronJob returns the spec of the Check monitoring the CronJob
+func checkSpecFromCronJob(cronJob batchv1beta1.CronJob) (monitoringv1alpha1.CheckSpec, error) {
+	spec := monitoringv1alpha1.CheckSpec{}
+	annotations := cronJob.Annotations
+
+	schedule := strings.TrimSpace(cronJob.Spec.Schedule)
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if strings.HasPrefix(schedule, prefix) {
+			p := strings.SplitN(strings.TrimPrefix(schedule, prefix), " ", 2)
+			if len(p) != 2 {
+				return spec, fmt.Errorf("invalid schedule %s", cronJob.Spec.Schedule)
+			}
+			spec.Timezone = p[0]
+			schedule = strings.TrimSpace(p[1])
+		}
+	}
+	if strings.HasPrefix(schedule, "@") {
+		s, ok := scheduleDescriptors[schedule]
+		if !ok {
+			return spec, fmt.Errorf("unsupported schedule %s", schedule)
+		}
+		schedule = s
+	}
+	spec.Schedule = schedule
+
+	if tz, ok := annotations[AnnotationTimezone]; ok {
+		spec.Timezone = tz
+	}
+
+	if v, ok := annotations[AnnotationGracePeriod]; ok {
+		grace, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return spec, fmt.Errorf("annotation %s must be a number of seconds", AnnotationGracePeriod)
+		}
+		if grace < minGracePeriod || grace > maxGracePeriod {
+			return spec, fmt.Errorf("annotation %s must be between %d and %d seconds", AnnotationGracePeriod, minGracePeriod, maxGracePeriod)
+		}
+		grace32 := int32(grace)
+		spec.GracePeriod = &grace32
+	}
+
+	spec.Tags = SplitList(annotations[AnnotationTags])
+	spec.Channels = SplitList(annotations[AnnotationChannels])
+	if _, ok := annotations[AnnotationChannels]; ok && len(spec.Channels) == 0 {
+		return spec, fmt.Errorf("annotation %s must list at least one channel", AnnotationChannels)
+	}
+	if len(spec.Channels) > maxCheckListItems {
+		return spec, fmt.Errorf("annotation %s must list at most %d channels", AnnotationChannels, maxCheckListItems)
+	}
+	if len(spec.Tags) > maxCheckListItems {
+		return spec, fmt.Errorf("annotation %s must list at most %d tags", AnnotationTags, maxCheckListItems)
+	}
+
+	// the Check webhook would reject the Check as well
+	if errs := monitoringv1alpha1.ValidateCheckSpec(spec, field.NewPath("spec")); len(errs) > 0 {
+		return spec, errs.ToAggregate()
+	}
+
+	return spec, nil
+}
+
+// SetupWithManager hooks up the controller/reconciler
+func (r *CronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&batchv1beta1.CronJob{}).
+		Owns(&monitoringv1alpha1.Check{}).
+		WithEventFilter(predicate.Funcs{UpdateFunc: cronJobChanged}).
+		Complete(r)
+}
+
+// cronJobChanged ignores updates of a CronJob leaving both its spec and annotations unchanged, such as
+// status updates, so a rejected Check is only retried once the CronJob changes
+func cronJobChanged(e event.UpdateEvent) bool {
+	if _, ok := e.ObjectNew.(*batchv1beta1.CronJob); !ok {
+		return true
+	}
+	return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+		!reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations())
+}
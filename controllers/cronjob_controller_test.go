@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestCronJobController_CheckSpecFromCronJob(t *testing.T) {
+	g := NewGomegaWithT(t)
+	grace := int32(300)
+
+	spec, err := checkSpecFromCronJob(*newCronJob("*/5 * * * *", map[string]string{
+		AnnotationTimezone:    "Europe/Stockholm",
+		AnnotationGracePeriod: "300",
+		AnnotationTags:        "prod, backup",
+		AnnotationChannels:    "email,slack/ops,",
+	}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(spec).To(Equal(monitoringv1alpha1.CheckSpec{
+		Schedule:    "*/5 * * * *",
+		Timezone:    "Europe/Stockholm",
+		GracePeriod: &grace,
+		Tags:        []string{"prod", "backup"},
+		Channels:    []string{"email", "slack/ops"},
+	}))
+
+	spec, err = checkSpecFromCronJob(*newCronJob("CRON_TZ=Europe/Stockholm 0 3 * * *", nil))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(spec.Schedule).To(Equal("0 3 * * *"))
+	g.Expect(spec.Timezone).To(Equal("Europe/Stockholm"))
+
+	spec, err = checkSpecFromCronJob(*newCronJob("TZ=UTC @daily", map[string]string{AnnotationTimezone: "Europe/Oslo"}))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(spec.Schedule).To(Equal("0 0 * * *"))
+	g.Expect(spec.Timezone).To(Equal("Europe/Oslo"))
+
+	_, err = checkSpecFromCronJob(*newCronJob("@every 5m", nil))
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = checkSpecFromCronJob(*newCronJob("* * * * *", map[string]string{AnnotationGracePeriod: "5m"}))
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = checkSpecFromCronJob(*newCronJob("* * * * *", map[string]string{AnnotationGracePeriod: "30"}))
+	g.Expect(err).To(MatchError("annotation healthchecks.io/grace-period must be between 60 and 2592000 seconds"))
+
+	_, err = checkSpecFromCronJob(*newCronJob("* * * * *", map[string]string{AnnotationChannels: " , "}))
+	g.Expect(err).To(MatchError("annotation healthchecks.io/channels must list at least one channel"))
+
+	_, err = checkSpecFromCronJob(*newCronJob("CRON_TZ=Mars/Olympus 0 3 * * *", nil))
+	g.Expect(err).To(MatchError(ContainSubstring("spec.timezone")))
+
+	_, err = checkSpecFromCronJob(*newCronJob("* * * * *", map[string]string{AnnotationChannels: "email,*"}))
+	g.Expect(err).To(MatchError(ContainSubstring("spec.channels[1]")))
+
+	_, err = checkSpecFromCronJob(*newCronJob("0 0 31 2 * *", nil))
+	g.Expect(err).To(MatchError(ContainSubstring("spec.schedule")))
+}
+
+func TestCronJobController_CreateCheck(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	cronJob := newCronJob("@hourly", map[string]string{
+		AnnotationMonitor: "true",
+		AnnotationTags:    "backup",
+	})
+	r := newCronJobReconciler(t, cronJob)
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest(cronJob.Name, cronJob.Namespace))
+
+	// Assert
+	g.Expect(err).ToNot(HaveOccurred())
+
+	check := &monitoringv1alpha1.Check{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, check)).To(Succeed())
+	g.Expect(check.Spec.Schedule).To(Equal("0 * * * *"))
+	g.Expect(check.Spec.Tags).To(Equal([]string{"backup"}))
+	g.Expect(metav1.IsControlledBy(check, cronJob)).To(BeTrue())
+	g.Expect(<-r.Recorder.(*record.FakeRecorder).Events).To(Equal("Normal CheckCreated Created Check backup"))
+}
+
+func TestCronJobController_InvalidAnnotations(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	cronJob := newCronJob("@hourly", map[string]string{
+		AnnotationMonitor:     "true",
+		AnnotationGracePeriod: "10",
+	})
+	r := newCronJobReconciler(t, cronJob)
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest(cronJob.Name, cronJob.Namespace))
+
+	// Assert, no Check is created
+	g.Expect(err).ToNot(HaveOccurred())
+	err = r.Get(context.TODO(), types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, &monitoringv1alpha1.Check{})
+	g.Expect(ignoreNotFound(err)).ToNot(HaveOccurred())
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(<-r.Recorder.(*record.FakeRecorder).Events).To(HavePrefix("Warning InvalidAnnotations"))
+}
+
+func TestCronJobController_CheckRejected(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	cronJob := newCronJob("@hourly", map[string]string{AnnotationMonitor: "true"})
+	r := newCronJobReconciler(t, cronJob)
+	r.Client = &rejectingClient{Client: r.Client}
+
+	// Act
+	result, err := r.Reconcile(NewReconcileRequest(cronJob.Name, cronJob.Namespace))
+
+	// Assert, the rejection is reported and not retried
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.Requeue).To(BeFalse())
+	g.Expect(<-r.Recorder.(*record.FakeRecorder).Events).To(HavePrefix("Warning CheckRejected"))
+}
+
+func TestCronJobController_CronJobChanged(t *testing.T) {
+	g := NewGomegaWithT(t)
+	old := newCronJob("@hourly", map[string]string{AnnotationMonitor: "true"})
+	old.Generation = 1
+
+	status := old.DeepCopy()
+	status.Status.LastScheduleTime = &metav1.Time{}
+	g.Expect(cronJobChanged(event.UpdateEvent{MetaOld: old, ObjectOld: old, MetaNew: status, ObjectNew: status})).To(BeFalse())
+
+	spec := old.DeepCopy()
+	spec.Generation = 2
+	g.Expect(cronJobChanged(event.UpdateEvent{MetaOld: old, ObjectOld: old, MetaNew: spec, ObjectNew: spec})).To(BeTrue())
+
+	annotations := old.DeepCopy()
+	annotations.Annotations[AnnotationGracePeriod] = "300"
+	g.Expect(cronJobChanged(event.UpdateEvent{MetaOld: old, ObjectOld: old, MetaNew: annotations, ObjectNew: annotations})).To(BeTrue())
+
+	check := &monitoringv1alpha1.Check{}
+	g.Expect(cronJobChanged(event.UpdateEvent{MetaOld: check, ObjectOld: check, MetaNew: check, ObjectNew: check})).To(BeTrue())
+}
+
+func TestCronJobController_DeleteCheckWhenNotMonitored(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	cronJob := newCronJob("@hourly", nil)
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: cronJob.Name, Namespace: cronJob.Namespace},
+	}
+	g.Expect(controllerutil.SetControllerReference(cronJob, check, scheme.Scheme)).To(Succeed())
+	r := newCronJobReconciler(t, cronJob, check)
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest(cronJob.Name, cronJob.Namespace))
+
+	// Assert
+	g.Expect(err).ToNot(HaveOccurred())
+	err = r.Get(context.TODO(), types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, &monitoringv1alpha1.Check{})
+	g.Expect(ignoreNotFound(err)).ToNot(HaveOccurred())
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCronJobController_CheckConflict(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	cronJob := newCronJob("@hourly", map[string]string{AnnotationMonitor: "true"})
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: cronJob.Name, Namespace: cronJob.Namespace},
+		Spec:       monitoringv1alpha1.CheckSpec{Schedule: "* * * * *"},
+	}
+	r := newCronJobReconciler(t, cronJob, check)
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest(cronJob.Name, cronJob.Namespace))
+
+	// Assert, the Check created by hand is left untouched
+	g.Expect(err).ToNot(HaveOccurred())
+	actual := &monitoringv1alpha1.Check{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, actual)).To(Succeed())
+	g.Expect(actual.Spec.Schedule).To(Equal("* * * * *"))
+	g.Expect(<-r.Recorder.(*record.FakeRecorder).Events).To(HavePrefix("Warning CheckConflict"))
+}
+
+func newCronJob(schedule string, annotations map[string]string) *batchv1beta1.CronJob {
+	return &batchv1beta1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "backup",
+			Namespace:   "default",
+			UID:         "cronjob-uid",
+			Annotations: annotations,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule: schedule,
+		},
+	}
+}
+
+// rejectingClient rejects the creation of objects as invalid, like the Check webhook does
+type rejectingClient struct {
+	client.Client
+}
+
+func (c *rejectingClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	return apierrs.NewInvalid(monitoringv1alpha1.GroupVersion.WithKind("Check").GroupKind(), "backup", field.ErrorList{
+		field.Invalid(field.NewPath("spec", "schedule"), "@hourly", "rejected"),
+	})
+}
+
+func newCronJobReconciler(t *testing.T, objs ...runtime.Object) *CronJobReconciler {
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{})
+
+	return &CronJobReconciler{
+		Client:   fake.NewFakeClientWithScheme(s, objs...),
+		Scheme:   s,
+		Log:      testutil.LogrTestLogger{T: t},
+		Recorder: record.NewFakeRecorder(100),
+	}
+}
@@ -0,0 +1,326 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+var (
+	orphanedChecks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "healthchecksio_operator_orphaned_checks",
+		Help: "Number of checks in healthchecks.io owned by the operator without a matching Check",
+	})
+	orphanedChecksDeleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "healthchecksio_operator_orphaned_checks_deleted_total",
+		Help: "Total number of orphaned checks deleted from healthchecks.io",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(orphanedChecks, orphanedChecksDeleted)
+}
+
+// Reasons used for events recorded about orphaned checks
+const (
+	EventReasonOrphaned      = "Orphaned"
+	EventReasonOrphanDeleted = "OrphanDeleted"
+)
+
+var _ manager.Runnable = &GarbageCollector{}
+var _ manager.LeaderElectionRunnable = &GarbageCollector{}
+
+// GarbageCollector finds checks in healthchecks.io that are owned by the operator
+// but no longer have a matching Check, and optionally deletes them. Every account
+// used by the operator is swept, the default one and the ones of projects and Checks.
+type GarbageCollector struct {
+	Client       client.Reader
+	Log          logr.Logger
+	Recorder     record.EventRecorder
+	Hckio        HealthchecksAPI
+	Clients      *ClientCache
+	NewAPI       APIFactory
+	Clock        Clock
+	Interval     time.Duration
+	GracePeriod  time.Duration
+	Delete       bool
+	DryRun       bool
+	NamePrefix   string
+	OwnershipTag string
+
+	// APIReader reads API key Secrets from the API server, Secrets are not cached
+	APIReader client.Reader
+
+	// EventObject is the object events about orphans are recorded on when they are not found
+	// through a HealthchecksProject or ClusterHealthchecksProject, e.g. the pod of the operator
+	EventObject runtime.Object
+
+	// SelfCheckName is the name of the self check of the operator, which has no matching Check
+	SelfCheckName string
+
+	orphanedSince map[string]time.Time
+}
+
+// gcAccount is an account swept by the garbage collector
+type gcAccount struct {
+	project checkProject
+
+	// object events about the orphans of the account are recorded on, nil when there is none
+	object runtime.Object
+}
+
+// Start runs the garbage collector every interval until stop is closed
+func (gc *GarbageCollector) Start(stop <-chan struct{}) error {
+	if gc.OwnershipTag == "" && gc.NamePrefix == "" {
+		gc.Log.V(0).Info("neither an ownership tag nor a name prefix is set, only the checks of projects with a name prefix are collected")
+	}
+
+	wait.Until(func() {
+		if err := gc.Collect(context.Background()); err != nil {
+			gc.Log.Error(err, "garbage collection of orphaned checks failed")
+		}
+	}, gc.Interval, stop)
+
+	return nil
+}
+
+// NeedLeaderElection ensures a single replica of the operator collects orphaned checks
+func (gc *GarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Collect finds the orphaned checks of every account and deletes the ones orphaned for longer than the grace period
+func (gc *GarbageCollector) Collect(ctx context.Context) error {
+	var checks monitoringv1alpha1.CheckList
+	if err := gc.Client.List(ctx, &checks); err != nil {
+		return err
+	}
+
+	accounts, err := gc.accounts(ctx, checks.Items)
+	if err != nil {
+		return err
+	}
+
+	now := gc.Clock.Now().Time
+	orphanedSince := make(map[string]time.Time)
+	fetched := make(map[string][]*healthchecksio.HealthcheckResponse)
+	deleted := make(map[string]bool)
+	count := 0
+	var collectErr error
+	for _, account := range accounts {
+		api := account.project.client
+		healthchecks, ok := fetched[api.Key()]
+		if !ok {
+			healthchecks, err = api.GetAll()
+			if err != nil {
+				// the orphans of the account keep the time they were found, the account is swept again next time
+				gc.Log.Error(err, "unable to list checks of account", "baseURL", api.BaseURL(), "account", api.Account())
+				collectErr = err
+				continue
+			}
+			fetched[api.Key()] = healthchecks
+		}
+
+		orphans := gc.orphans(account.project, checks.Items, healthchecks)
+		count += len(orphans)
+		for _, hc := range orphans {
+			if gc.collect(account, hc, now, orphanedSince) {
+				deleted[hc.ID()] = true
+			}
+		}
+	}
+	orphanedChecks.Set(float64(count))
+
+	// the orphans of an account that could not be listed keep the time they were found
+	if collectErr != nil {
+		for id, since := range gc.orphanedSince {
+			if _, ok := orphanedSince[id]; !ok && !deleted[id] {
+				orphanedSince[id] = since
+			}
+		}
+	}
+	gc.orphanedSince = orphanedSince
+
+	return collectErr
+}
+
+// collect records when the orphan was found and deletes it once the grace period has passed.
+// Returns true when the orphan was deleted.
+func (gc *GarbageCollector) collect(account gcAccount, hc *healthchecksio.HealthcheckResponse, now time.Time, orphanedSince map[string]time.Time) bool {
+	id := hc.ID()
+	since, ok := gc.orphanedSince[id]
+	if !ok {
+		since = now
+		gc.Log.V(0).Info(fmt.Sprintf("found orphaned healthcheck: %s", id), "name", hc.Name)
+		gc.event(account, corev1.EventTypeWarning, EventReasonOrphaned, "Found orphaned check %s (%s) in healthchecks.io", hc.Name, id)
+	}
+	orphanedSince[id] = since
+
+	if !gc.Delete || now.Sub(since) < gc.GracePeriod {
+		return false
+	}
+
+	if gc.DryRun {
+		gc.Log.V(0).Info(fmt.Sprintf("dry-run, skipped deletion of orphaned healthcheck: %s", id), "name", hc.Name)
+		return false
+	}
+
+	if _, err := account.project.client.Delete(id); err != nil {
+		if statusCode(err) != http.StatusNotFound {
+			gc.Log.Error(err, "unable to delete orphaned healthcheck", "id", id, "name", hc.Name)
+			return false
+		}
+	}
+	gc.Log.V(0).Info(fmt.Sprintf("deleted orphaned healthcheck: %s", id), "name", hc.Name)
+	gc.event(account, corev1.EventTypeNormal, EventReasonOrphanDeleted, "Deleted orphaned check %s (%s) from healthchecks.io", hc.Name, id)
+	orphanedChecksDeleted.Inc()
+	delete(orphanedSince, id)
+	return true
+}
+
+// event records an event about an orphan of the account, when the account has an object to record it on
+func (gc *GarbageCollector) event(account gcAccount, eventtype, reason, messageFmt string, args ...interface{}) {
+	if gc.Recorder == nil || account.object == nil {
+		return
+	}
+	gc.Recorder.Eventf(account.object, eventtype, reason, messageFmt, args...)
+}
+
+// accounts returns the accounts used by the operator, each with the name prefix and ownership tag
+// of its checks: the default account, the accounts of the projects and the accounts of the checks
+func (gc *GarbageCollector) accounts(ctx context.Context, checks []monitoringv1alpha1.Check) ([]gcAccount, error) {
+	resolver := projectResolver{
+		client:  gc.Client,
+		secrets: uncachedReader(gc.Client, gc.APIReader),
+		clients: gc.Clients,
+		newAPI:  gc.NewAPI,
+		defaults: checkProject{
+			client:       gc.Hckio,
+			namePrefix:   gc.NamePrefix,
+			ownershipTag: gc.OwnershipTag,
+		},
+	}
+
+	accounts := make([]gcAccount, 0)
+	seen := make(map[string]bool)
+	add := func(project checkProject, object runtime.Object) {
+		key := project.client.Key() + "|" + project.namePrefix
+		if seen[key] || (project.ownershipTag == "" && project.namePrefix == "") {
+			return
+		}
+		seen[key] = true
+		accounts = append(accounts, gcAccount{project: project, object: object})
+	}
+
+	add(resolver.defaults, gc.EventObject)
+
+	var projects monitoringv1alpha1.HealthchecksProjectList
+	if err := gc.Client.List(ctx, &projects); err != nil {
+		return nil, err
+	}
+	for i := range projects.Items {
+		hp := &projects.Items[i]
+		project, err := resolver.fromSpec(ctx, hp.Spec, hp.Namespace, true)
+		if err != nil {
+			gc.Log.Error(err, "unable to resolve HealthchecksProject", "namespace", hp.Namespace, "name", hp.Name)
+			continue
+		}
+		add(project, hp)
+	}
+
+	var clusterProjects monitoringv1alpha1.ClusterHealthchecksProjectList
+	if err := gc.Client.List(ctx, &clusterProjects); err != nil {
+		return nil, err
+	}
+	for i := range clusterProjects.Items {
+		chp := &clusterProjects.Items[i]
+		project, err := resolver.fromSpec(ctx, chp.Spec, chp.Spec.APIKeySecretRef.Namespace, false)
+		if err != nil {
+			gc.Log.Error(err, "unable to resolve ClusterHealthchecksProject", "name", chp.Name)
+			continue
+		}
+		add(project, chp)
+	}
+
+	// checks referencing an API key Secret, checks referencing a project are covered by the project
+	for i := range checks {
+		check := &checks[i]
+		if check.Spec.APIKeySecretRef == nil || check.Spec.ProjectRef != nil {
+			continue
+		}
+		project, err := resolver.resolve(ctx, check)
+		if err != nil {
+			gc.Log.Error(err, "unable to resolve the account of Check", "namespace", check.Namespace, "name", check.Name)
+			continue
+		}
+		add(project, gc.EventObject)
+	}
+
+	return accounts, nil
+}
+
+// orphans returns the healthchecks owned by the operator in the project without a matching Check.
+// Every Check is matched, not only the ones of the project, so a Check whose project could not be
+// resolved never has its check deleted.
+func (gc *GarbageCollector) orphans(project checkProject, checks []monitoringv1alpha1.Check, healthchecks []*healthchecksio.HealthcheckResponse) []*healthchecksio.HealthcheckResponse {
+	ids := make(map[string]bool, len(checks))
+	names := make(map[string]bool, len(checks))
+	for _, c := range checks {
+		ids[c.Status.ID] = true
+		ids[c.Spec.AdoptID] = true
+		names[project.convertToHealthcheck(c).Name] = true
+	}
+
+	orphans := make([]*healthchecksio.HealthcheckResponse, 0)
+	for _, hc := range healthchecks {
+		if !isOwned(project, hc) || ids[hc.ID()] || names[hc.Name] || (gc.SelfCheckName != "" && hc.Name == gc.SelfCheckName) {
+			continue
+		}
+		orphans = append(orphans, hc)
+	}
+
+	return orphans
+}
+
+// isOwned returns true when the healthcheck has the ownership tag and the name prefix of the project, as far as they are set
+func isOwned(project checkProject, hc *healthchecksio.HealthcheckResponse) bool {
+	if project.ownershipTag == "" && project.namePrefix == "" {
+		return false
+	}
+
+	if project.ownershipTag != "" && !containsString(strings.Fields(hc.Tags), project.ownershipTag) {
+		return false
+	}
+
+	return project.namePrefix == "" || strings.HasPrefix(hc.Name, project.namePrefix+"/")
+}
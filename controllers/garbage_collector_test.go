@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/gomega"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+const gcHealthchecksResponse = `{
+	"checks": [
+		{
+			"name": "cluster-1/default/foo",
+			"tags": "k8s-operator",
+			"update_url": "https://healthchecks.io/api/v1/checks/7b1ab3b5-a7b9-4c2e-8d1f-5f5ad6c2a2b1"
+		},
+		{
+			"name": "cluster-1/default/bar",
+			"tags": "k8s-operator",
+			"update_url": "https://healthchecks.io/api/v1/checks/0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"
+		},
+		{
+			"name": "manual",
+			"tags": "",
+			"update_url": "https://healthchecks.io/api/v1/checks/11111111-2222-3333-4444-555555555555"
+		}
+	]
+}`
+
+func TestGarbageCollector_IsOwned(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tagged := &healthchecksio.HealthcheckResponse{Name: "other/default/foo", Tags: "foo k8s-operator"}
+	prefixed := &healthchecksio.HealthcheckResponse{Name: "cluster-1/default/foo"}
+
+	g.Expect(isOwned(checkProject{ownershipTag: "k8s-operator"}, tagged)).To(BeTrue())
+	g.Expect(isOwned(checkProject{ownershipTag: "k8s-operator", namePrefix: "cluster-1"}, prefixed)).To(BeFalse())
+	g.Expect(isOwned(checkProject{ownershipTag: "k8s-operator", namePrefix: "cluster-1"}, tagged)).To(BeFalse())
+	g.Expect(isOwned(checkProject{ownershipTag: "k8s-operator", namePrefix: "other"}, tagged)).To(BeTrue())
+	g.Expect(isOwned(checkProject{namePrefix: "cluster-1"}, prefixed)).To(BeTrue())
+	g.Expect(isOwned(checkProject{namePrefix: "cluster"}, prefixed)).To(BeFalse())
+	g.Expect(isOwned(checkProject{}, prefixed)).To(BeFalse())
+}
+
+func TestGarbageCollector_Orphans(t *testing.T) {
+	g := NewGomegaWithT(t)
+	gc := &GarbageCollector{SelfCheckName: "cluster-1/healthchecksio-operator"}
+
+	healthchecks := []*healthchecksio.HealthcheckResponse{
+		{Name: "cluster-1/default/foo", UpdateURL: "https://healthchecks.io/api/v1/checks/1"},
+		{Name: "cluster-1/default/bar", UpdateURL: "https://healthchecks.io/api/v1/checks/2"},
+		{Name: "cluster-1/default/renamed", UpdateURL: "https://healthchecks.io/api/v1/checks/3"},
+		{Name: "cluster-1/default/adopted", UpdateURL: "https://healthchecks.io/api/v1/checks/4"},
+		{Name: "cluster-2/default/baz", UpdateURL: "https://healthchecks.io/api/v1/checks/5"},
+		{Name: "cluster-1/healthchecksio-operator", UpdateURL: "https://healthchecks.io/api/v1/checks/6"},
+	}
+	checks := []monitoringv1alpha1.Check{
+		{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+		{Status: monitoringv1alpha1.CheckStatus{ID: "3"}},
+		{Spec: monitoringv1alpha1.CheckSpec{AdoptID: "4"}},
+	}
+
+	orphans := gc.orphans(checkProject{namePrefix: "cluster-1"}, checks, healthchecks)
+
+	g.Expect(orphans).To(HaveLen(1))
+	g.Expect(orphans[0].ID()).To(Equal("2"))
+}
+
+func TestGarbageCollector_Collect(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	now := metav1.Now()
+	server, deleted := newGarbageCollectorServer()
+	defer server.Close()
+
+	gc := newGarbageCollector(t, server.URL, &now, &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+	})
+	gc.Delete = true
+
+	// Act & assert, orphans are not deleted within the grace period
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+	g.Expect(gc.orphanedSince).To(HaveKey("0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"))
+	g.Expect(gc.orphanedSince).To(HaveLen(1))
+	g.Expect(*deleted).To(BeEmpty())
+
+	// Act & assert, orphans are deleted after the grace period
+	now = metav1.NewTime(now.Add(2 * time.Hour))
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+	g.Expect(gc.orphanedSince).To(BeEmpty())
+	g.Expect(*deleted).To(ConsistOf("0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"))
+}
+
+func TestGarbageCollector_Collect_DryRun(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	now := metav1.Now()
+	server, deleted := newGarbageCollectorServer()
+	defer server.Close()
+
+	gc := newGarbageCollector(t, server.URL, &now)
+	gc.Delete = true
+	gc.DryRun = true
+
+	// Act
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+	now = metav1.NewTime(now.Add(2 * time.Hour))
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+
+	// Assert
+	g.Expect(gc.orphanedSince).To(HaveLen(2))
+	g.Expect(*deleted).To(BeEmpty())
+}
+
+func TestGarbageCollector_Collect_RetainedCheck(t *testing.T) {
+	// Arrange, a check retained by an operator using only a name prefix
+	g := NewGomegaWithT(t)
+	now := metav1.Now()
+	hckio := testutil.NewFakeHealthchecks()
+	defer hckio.Close()
+	id := hckio.AddCheck(testutil.FakeCheck{Name: "cluster-1/default/foo"})
+
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status:     monitoringv1alpha1.CheckStatus{ID: id},
+	}
+	ctx := NewCheckReconcilerTest(t, WithK8sObjects(check), WithHckioBaseURL(hckio.URL))
+	defer func() { ctx.Close() }()
+	ctx.Reconciler.NamePrefix = "cluster-1"
+	ctx.Reconciler.OwnershipTag = ""
+
+	gc := newGarbageCollector(t, hckio.URL, &now)
+	gc.OwnershipTag = ""
+	gc.Delete = true
+
+	// Act
+	g.Expect(ctx.Reconciler.retainExternalResources(check)).To(Succeed())
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+	now = metav1.NewTime(now.Add(2 * time.Hour))
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+
+	// Assert
+	hc, ok := hckio.Check(id)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hc.Name).To(Equal("default/foo"))
+	g.Expect(gc.orphanedSince).To(BeEmpty())
+}
+
+func TestGarbageCollector_Collect_ProjectAccounts(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	now := metav1.Now()
+	server, deleted := newGarbageCollectorServer()
+	defer server.Close()
+	hckio := testutil.NewFakeHealthchecks()
+	defer hckio.Close()
+	hckio.APIKey = "project-api-key"
+	kept := hckio.AddCheck(testutil.FakeCheck{Name: "team-a/default/foo", Tags: "k8s-operator"})
+	orphan := hckio.AddCheck(testutil.FakeCheck{Name: "team-a/default/bar", Tags: "k8s-operator"})
+	manual := hckio.AddCheck(testutil.FakeCheck{Name: "team-a/default/manual"})
+
+	project := &monitoringv1alpha1.HealthchecksProject{
+		ObjectMeta: metav1.ObjectMeta{Name: "project", Namespace: "team-a"},
+		Spec: monitoringv1alpha1.HealthchecksProjectSpec{
+			APIKeySecretRef: newProjectSecretKeyReference(""),
+			BaseURL:         hckio.URL,
+			NamePrefix:      "team-a",
+		},
+	}
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       monitoringv1alpha1.CheckSpec{ProjectRef: &monitoringv1alpha1.ProjectReference{Name: "project"}},
+	}
+	gc := newGarbageCollector(t, server.URL, &now, newProjectAPIKeySecret("team-a"), project, check)
+	gc.Delete = true
+	gc.Clients.ProjectPolicy.AllowedBaseURLs = []string{hckio.URL}
+	recorder := gc.Recorder.(*record.FakeRecorder)
+	projectAPI := &recordingAPI{}
+	gc.NewAPI = func(client *healthchecksio.Client, opts ClientOptions) HealthchecksAPI {
+		projectAPI.HealthchecksAPI = NewHealthchecksioAPIWithOptions(client, opts)
+		return projectAPI
+	}
+
+	// Act & assert, the orphan of the project is found and reported on the project
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+	g.Expect(gc.orphanedSince).To(HaveKey(orphan))
+	g.Expect(gc.orphanedSince).ToNot(HaveKey(kept))
+	g.Expect(gc.orphanedSince).ToNot(HaveKey(manual))
+	g.Expect(recorder.Events).To(Receive(Equal(fmt.Sprintf("Warning Orphaned Found orphaned check team-a/default/bar (%s) in healthchecks.io", orphan))))
+
+	// Act & assert, the orphan is deleted through the client of the project after the grace period
+	now = metav1.NewTime(now.Add(2 * time.Hour))
+	g.Expect(gc.Collect(context.TODO())).To(Succeed())
+	_, ok := hckio.Check(orphan)
+	g.Expect(ok).To(BeFalse())
+	g.Expect(hckio.Checks()).To(HaveLen(2))
+	g.Expect(*deleted).To(ConsistOf("0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"))
+	g.Expect(projectAPI.deleted).To(ConsistOf(orphan), "deleted through the api created by the api factory")
+	g.Expect(recorder.Events).To(Receive(Equal(fmt.Sprintf("Normal OrphanDeleted Deleted orphaned check team-a/default/bar (%s) from healthchecks.io", orphan))))
+}
+
+// newGarbageCollectorServer creates a fake healthchecks.io server listing the checks
+// of gcHealthchecksResponse and recording the IDs of deleted checks
+func newGarbageCollectorServer() (*httptest.Server, *[]string) {
+	deleted := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			deleted = append(deleted, strings.TrimPrefix(req.URL.Path, "/checks/"))
+			res.Write([]byte(`{}`))
+			return
+		}
+		res.Write([]byte(gcHealthchecksResponse))
+	}))
+	return server, &deleted
+}
+
+func newGarbageCollector(t *testing.T, baseURL string, now *metav1.Time, objs ...runtime.Object) *GarbageCollector {
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion,
+		&monitoringv1alpha1.Check{}, &monitoringv1alpha1.CheckList{},
+		&monitoringv1alpha1.HealthchecksProject{}, &monitoringv1alpha1.HealthchecksProjectList{},
+		&monitoringv1alpha1.ClusterHealthchecksProject{}, &monitoringv1alpha1.ClusterHealthchecksProjectList{},
+	)
+
+	return &GarbageCollector{
+		Client:   fake.NewFakeClientWithScheme(s, objs...),
+		Log:      testutil.LogrTestLogger{T: t},
+		Recorder: record.NewFakeRecorder(100),
+		Hckio:    NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", baseURL)),
+		Clients: NewClientCache(func(apiKey string) *healthchecksio.Client {
+			return testutil.NewTestHealthchecksioClient(t, apiKey, baseURL)
+		}),
+		Clock:        Clock{Source: func() *metav1.Time { return now }},
+		GracePeriod:  time.Hour,
+		NamePrefix:   "cluster-1",
+		OwnershipTag: "k8s-operator",
+	}
+}
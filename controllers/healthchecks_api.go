@@ -0,0 +1,383 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+)
+
+// HealthchecksAPI manages checks in healthchecks.io, or another backend implementing its API
+type HealthchecksAPI interface {
+	// BaseURL returns the base URL of the API
+	BaseURL() string
+
+	// Account identifies the account the API is called with, without revealing its credentials
+	Account() string
+
+	// Key identifies the API and the account it is called with, without revealing its credentials
+	Key() string
+
+	// Create creates a check, or updates the existing check matching the unique fields of the check
+	Create(check healthchecksio.Healthcheck) (*healthchecksio.HealthcheckResponse, error)
+
+	// Update updates the check with the id
+	Update(id string, check healthchecksio.Healthcheck) (*healthchecksio.HealthcheckResponse, error)
+
+	// SetTags replaces the tags of the check with the id, removing every tag when tags is empty
+	SetTags(id string, tags []string) (*healthchecksio.HealthcheckResponse, error)
+
+	// Get returns the check with the id, or nil when it does not exist
+	Get(id string) (*healthchecksio.HealthcheckResponse, error)
+
+	// GetAll returns every check of the account
+	GetAll() ([]*healthchecksio.HealthcheckResponse, error)
+
+	// Delete deletes the check with the id
+	Delete(id string) (*healthchecksio.HealthcheckResponse, error)
+
+	// Pause pauses the check with the id until it is pinged again
+	Pause(id string) (*healthchecksio.HealthcheckResponse, error)
+
+	// GetAllChannels returns every notification channel of the account
+	GetAllChannels() ([]*healthchecksio.HealthcheckChannelResponse, error)
+
+	// Pings returns the latest pings received by the check with the id
+	Pings(id string) ([]Ping, error)
+
+	// Flips returns the latest changes of the status of the check with the id
+	Flips(id string) ([]Flip, error)
+}
+
+// Ping is a ping received by a check
+type Ping struct {
+	Type       string  `json:"type"`
+	Date       string  `json:"date"`
+	N          int     `json:"n"`
+	Scheme     string  `json:"scheme"`
+	RemoteAddr string  `json:"remote_addr"`
+	Method     string  `json:"method"`
+	UserAgent  string  `json:"ua"`
+	Duration   float64 `json:"duration,omitempty"`
+}
+
+// Flip is a change of the status of a check, up when Up is 1 and down when it is 0
+type Flip struct {
+	Timestamp string `json:"timestamp"`
+	Up        int    `json:"up"`
+}
+
+// APIFactory creates the HealthchecksAPI calling the API with a client created by a ClientCache for opts
+type APIFactory func(client *healthchecksio.Client, opts ClientOptions) HealthchecksAPI
+
+// newAPI returns the HealthchecksAPI of the client created by factory, or a HealthchecksioAPI when factory is nil
+func newAPI(factory APIFactory, client *healthchecksio.Client, opts ClientOptions) HealthchecksAPI {
+	if factory == nil {
+		return NewHealthchecksioAPIWithOptions(client, opts)
+	}
+	return factory(client, opts)
+}
+
+var _ HealthchecksAPI = &HealthchecksioAPI{}
+
+// HealthchecksioAPI implements HealthchecksAPI with a go-healthchecksio client
+type HealthchecksioAPI struct {
+	client *healthchecksio.Client
+	opts   ClientOptions
+}
+
+// NewHealthchecksioAPI creates a new HealthchecksioAPI calling the API with client
+func NewHealthchecksioAPI(client *healthchecksio.Client) *HealthchecksioAPI {
+	return &HealthchecksioAPI{client: client}
+}
+
+// NewHealthchecksioAPIWithOptions creates a new HealthchecksioAPI calling the API with a client
+// created by a ClientCache for opts
+func NewHealthchecksioAPIWithOptions(client *healthchecksio.Client, opts ClientOptions) *HealthchecksioAPI {
+	return &HealthchecksioAPI{client: client, opts: opts}
+}
+
+// BaseURL returns the base URL of the API
+func (a *HealthchecksioAPI) BaseURL() string {
+	return a.client.BaseURL
+}
+
+// Account identifies the account by a hash of the API key
+func (a *HealthchecksioAPI) Account() string {
+	return AccountOf(a.client.APIKey)
+}
+
+// Key identifies the API by the options of its client and a hash of the API key,
+// the same inputs the ClientCache holds a client for
+func (a *HealthchecksioAPI) Key() string {
+	opts := a.opts
+	opts.BaseURL = a.client.BaseURL
+	return fmt.Sprintf("%s|%s", opts.key(), a.Account())
+}
+
+// Create creates a check, or updates the existing check matching the unique fields of the check
+func (a *HealthchecksioAPI) Create(check healthchecksio.Healthcheck) (hc *healthchecksio.HealthcheckResponse, err error) {
+	return hc, a.call(func(client *healthchecksio.Client) error {
+		hc, err = client.Create(check)
+		return err
+	})
+}
+
+// Update updates the check with the id
+func (a *HealthchecksioAPI) Update(id string, check healthchecksio.Healthcheck) (hc *healthchecksio.HealthcheckResponse, err error) {
+	return hc, a.call(func(client *healthchecksio.Client) error {
+		hc, err = client.Update(id, check)
+		return err
+	})
+}
+
+// SetTags replaces the tags of the check with the id, removing every tag when tags is empty. The tags are
+// always sent, unlike with Update, which leaves out empty tags and so never removes the last tag.
+func (a *HealthchecksioAPI) SetTags(id string, tags []string) (*healthchecksio.HealthcheckResponse, error) {
+	req := struct {
+		Tags string `json:"tags"`
+	}{
+		Tags: strings.Join(tags, " "),
+	}
+	hc := &healthchecksio.HealthcheckResponse{}
+	if err := a.post("/checks/"+id, req, hc); err != nil {
+		return nil, err
+	}
+	return hc, nil
+}
+
+// Get reads the check with the id, returning nil when it does not exist.
+// go-healthchecksio has no call to read a single check, the request mirrors the ones it sends.
+func (a *HealthchecksioAPI) Get(id string) (*healthchecksio.HealthcheckResponse, error) {
+	var healthcheck healthchecksio.HealthcheckResponse
+	found, err := a.get("/checks/"+id, &healthcheck)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &healthcheck, nil
+}
+
+// GetAll returns every check of the account
+func (a *HealthchecksioAPI) GetAll() (checks []*healthchecksio.HealthcheckResponse, err error) {
+	return checks, a.call(func(client *healthchecksio.Client) error {
+		checks, err = client.GetAll()
+		return err
+	})
+}
+
+// Delete deletes the check with the id
+func (a *HealthchecksioAPI) Delete(id string) (hc *healthchecksio.HealthcheckResponse, err error) {
+	return hc, a.call(func(client *healthchecksio.Client) error {
+		hc, err = client.Delete(id)
+		return err
+	})
+}
+
+// Pause pauses the check with the id until it is pinged again
+func (a *HealthchecksioAPI) Pause(id string) (hc *healthchecksio.HealthcheckResponse, err error) {
+	return hc, a.call(func(client *healthchecksio.Client) error {
+		hc, err = client.Pause(id)
+		return err
+	})
+}
+
+// GetAllChannels returns every notification channel of the account
+func (a *HealthchecksioAPI) GetAllChannels() (channels []*healthchecksio.HealthcheckChannelResponse, err error) {
+	return channels, a.call(func(client *healthchecksio.Client) error {
+		channels, err = client.GetAllChannels()
+		return err
+	})
+}
+
+// Pings returns the latest pings received by the check with the id
+func (a *HealthchecksioAPI) Pings(id string) ([]Ping, error) {
+	var res struct {
+		Pings []Ping `json:"pings"`
+	}
+	found, err := a.get("/checks/"+id+"/pings/", &res)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("check %s not found", id)
+	}
+
+	return res.Pings, nil
+}
+
+// Flips returns the latest changes of the status of the check with the id
+func (a *HealthchecksioAPI) Flips(id string) ([]Flip, error) {
+	flips := make([]Flip, 0)
+	found, err := a.get("/checks/"+id+"/flips/", &flips)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("check %s not found", id)
+	}
+
+	return flips, nil
+}
+
+// get decodes the response of a GET request for path into v, returning false when it is not found
+func (a *HealthchecksioAPI) get(path string, v interface{}) (found bool, err error) {
+	return found, a.call(func(client *healthchecksio.Client) error {
+		req, err := http.NewRequest(http.MethodGet, client.BaseURL+path, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Api-Key", client.APIKey)
+
+		res, err := client.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		if res.StatusCode >= 300 {
+			return newResponseError(req, res)
+		}
+
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			return err
+		}
+
+		found = true
+		return nil
+	})
+}
+
+// post sends body encoded as JSON in a POST request for path, decoding the response into v
+func (a *HealthchecksioAPI) post(path string, body interface{}, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	return a.call(func(client *healthchecksio.Client) error {
+		req, err := http.NewRequest(http.MethodPost, client.BaseURL+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Api-Key", client.APIKey)
+		req.Header.Set("Content-Type", "application/json")
+
+		res, err := client.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode >= 300 {
+			return newResponseError(req, res)
+		}
+
+		return json.NewDecoder(res.Body).Decode(v)
+	})
+}
+
+// responseError is the error of an unsuccessful response to a request go-healthchecksio has no call for
+type responseError struct {
+	method     string
+	url        string
+	statusCode int
+	message    string
+}
+
+// newResponseError returns the responseError of res, with the message of its body when it has one
+func newResponseError(req *http.Request, res *http.Response) *responseError {
+	var body struct {
+		Message string `json:"error"`
+	}
+	message := res.Status
+	if err := json.NewDecoder(res.Body).Decode(&body); err == nil && body.Message != "" {
+		message = body.Message
+	}
+
+	return &responseError{
+		method:     req.Method,
+		url:        req.URL.String(),
+		statusCode: res.StatusCode,
+		message:    message,
+	}
+}
+
+// Error returns the error message
+func (e *responseError) Error() string {
+	return fmt.Sprintf("%s %s: response error, %s", e.method, e.url, e.message)
+}
+
+// StatusCode returns the HTTP response status code
+func (e *responseError) StatusCode() int {
+	return e.statusCode
+}
+
+// statusCode returns the HTTP response status code of an error returned by a HealthchecksAPI,
+// or 0 when the error has none
+func statusCode(err error) int {
+	var coded interface{ StatusCode() int }
+	if errors.As(err, &coded) {
+		return coded.StatusCode()
+	}
+	return 0
+}
+
+// call calls go-healthchecksio with a copy of the client keeping the RateLimitedError of a request
+// throttled by the APILimiter, which go-healthchecksio reduces to the message of an APIError
+func (a *HealthchecksioAPI) call(fn func(client *healthchecksio.Client) error) error {
+	httpClient := *a.client.HTTPClient
+	transport := &rateLimitedTransport{next: httpClient.Transport}
+	if transport.next == nil {
+		transport.next = http.DefaultTransport
+	}
+	httpClient.Transport = transport
+
+	client := *a.client
+	client.HTTPClient = &httpClient
+	if err := fn(&client); err != nil {
+		if transport.limited != nil {
+			return transport.limited
+		}
+		return err
+	}
+	return nil
+}
+
+// rateLimitedTransport keeps the RateLimitedError of a request
+type rateLimitedTransport struct {
+	next    http.RoundTripper
+	limited *RateLimitedError
+}
+
+// RoundTrip sends the request, keeping its error when it is a RateLimitedError
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.next.RoundTrip(req)
+	var limited *RateLimitedError
+	if errors.As(err, &limited) {
+		t.limited = limited
+	}
+	return res, err
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func newHealthchecksioAPITestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Api-Key") != "api-key" {
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch req.URL.Path {
+		case "/checks/e71024f4-8537-4dd2-b742-ebe5a1685776":
+			res.Write([]byte(`{
+				"name": "default/foo",
+				"status": "up",
+				"update_url": "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776"
+			}`))
+		case "/checks/e71024f4-8537-4dd2-b742-ebe5a1685776/pings/":
+			res.Write([]byte(`{"pings": [{"type": "success", "date": "2019-11-10T12:00:00+00:00", "n": 2, "scheme": "https", "remote_addr": "10.0.0.1", "method": "POST", "ua": "curl/7.64.0", "duration": 1.5}]}`))
+		case "/checks/e71024f4-8537-4dd2-b742-ebe5a1685776/flips/":
+			res.Write([]byte(`[{"timestamp": "2019-11-10T12:00:00+00:00", "up": 1}, {"timestamp": "2019-11-10T11:00:00+00:00", "up": 0}]`))
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestHealthchecksioAPI_Get(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := newHealthchecksioAPITestServer()
+	defer server.Close()
+	api := NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", server.URL))
+
+	hc, err := api.Get("e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(hc.ID()).To(Equal("e71024f4-8537-4dd2-b742-ebe5a1685776"))
+	g.Expect(hc.Status).To(Equal("up"))
+
+	hc, err = api.Get("missing")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(hc).To(BeNil())
+
+	_, err = NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "wrong-key", server.URL)).Get("e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(statusCode(err)).To(Equal(http.StatusUnauthorized))
+	g.Expect(err.Error()).To(HavePrefix("GET " + server.URL + "/checks/e71024f4-8537-4dd2-b742-ebe5a1685776:"))
+}
+
+func TestHealthchecksioAPI_PingsAndFlips(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := newHealthchecksioAPITestServer()
+	defer server.Close()
+	api := NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", server.URL))
+
+	pings, err := api.Pings("e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pings).To(Equal([]Ping{{
+		Type:       "success",
+		Date:       "2019-11-10T12:00:00+00:00",
+		N:          2,
+		Scheme:     "https",
+		RemoteAddr: "10.0.0.1",
+		Method:     "POST",
+		UserAgent:  "curl/7.64.0",
+		Duration:   1.5,
+	}}))
+
+	flips, err := api.Flips("e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(flips).To(Equal([]Flip{
+		{Timestamp: "2019-11-10T12:00:00+00:00", Up: 1},
+		{Timestamp: "2019-11-10T11:00:00+00:00", Up: 0},
+	}))
+
+	_, err = api.Pings("missing")
+	g.Expect(err).To(MatchError("check missing not found"))
+	_, err = api.Flips("missing")
+	g.Expect(err).To(MatchError("check missing not found"))
+}
+
+func TestHealthchecksioAPI_Key(t *testing.T) {
+	g := NewGomegaWithT(t)
+	api := NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", "https://hc.example.com/api/v1"))
+
+	g.Expect(api.BaseURL()).To(Equal("https://hc.example.com/api/v1"))
+	g.Expect(api.Key()).To(HavePrefix("https://hc.example.com/api/v1|"))
+	g.Expect(api.Key()).ToNot(ContainSubstring("api-key"))
+	g.Expect(api.Key()).ToNot(Equal(NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "other-key", "https://hc.example.com/api/v1")).Key()))
+
+	client := testutil.NewTestHealthchecksioClient(t, "api-key", "https://hc.example.com/api/v1")
+	g.Expect(NewHealthchecksioAPIWithOptions(client, ClientOptions{}).Key()).To(Equal(api.Key()))
+	g.Expect(NewHealthchecksioAPIWithOptions(client, ClientOptions{InsecureSkipVerify: true}).Key()).ToNot(Equal(api.Key()))
+	g.Expect(NewHealthchecksioAPIWithOptions(client, ClientOptions{CABundle: []byte("ca")}).Key()).ToNot(Equal(api.Key()))
+}
@@ -0,0 +1,235 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/mitchellh/hashstructure"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// HealthchecksProjectReconciler reconciles a HealthchecksProject object
+type HealthchecksProjectReconciler struct {
+	client.Client
+	Log               logr.Logger
+	Clients           *ClientCache
+	Clock             Clock
+	ReconcileInterval time.Duration
+
+	// APIReader reads the API key Secret from the API server, Secrets are not cached
+	APIReader client.Reader
+
+	// NewAPI creates the HealthchecksAPI of the project, a HealthchecksioAPI when nil
+	NewAPI APIFactory
+
+	// Poller provides the number of checks of the project, avoiding a read of every check per reconcile
+	Poller *StatusPoller
+}
+
+// +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=healthchecksprojects,verbs=get;list;watch
+// +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=healthchecksprojects/status,verbs=get;update;patch
+
+// Reconcile tries to reconcile the object
+func (r *HealthchecksProjectReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("healthchecksproject", req.NamespacedName)
+
+	var project monitoringv1alpha1.HealthchecksProject
+	if err := r.Get(ctx, req.NamespacedName, &project); err != nil {
+		log.V(0).Info("unable to fetch HealthchecksProject from k8s")
+		return ctrl.Result{}, ignoreNotFound(err)
+	}
+	log.V(1).Info("fetched HealthchecksProject from k8s")
+
+	if !project.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if updateProjectStatus(ctx, uncachedReader(r.Client, r.APIReader), r.Clients, r.NewAPI, r.Poller, r.Clock, log, project.Spec, project.Namespace, true, project.Generation, &project.Status) {
+		if err := r.Status().Update(ctx, &project); err != nil {
+			log.Error(err, "unable to update HealthchecksProject status")
+			return ctrl.Result{}, err
+		}
+		log.V(1).Info("updated the HealthchecksProject status")
+	}
+
+	return ctrl.Result{RequeueAfter: r.ReconcileInterval}, nil
+}
+
+// SetupWithManager hooks up the controller/reconciler
+func (r *HealthchecksProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&monitoringv1alpha1.HealthchecksProject{}).
+		Complete(r)
+}
+
+// ClusterHealthchecksProjectReconciler reconciles a ClusterHealthchecksProject object
+type ClusterHealthchecksProjectReconciler struct {
+	client.Client
+	Log               logr.Logger
+	Clients           *ClientCache
+	Clock             Clock
+	ReconcileInterval time.Duration
+
+	// APIReader reads the API key Secret from the API server, Secrets are not cached
+	APIReader client.Reader
+
+	// NewAPI creates the HealthchecksAPI of the project, a HealthchecksioAPI when nil
+	NewAPI APIFactory
+
+	// Poller provides the number of checks of the project, avoiding a read of every check per reconcile
+	Poller *StatusPoller
+}
+
+// +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=clusterhealthchecksprojects,verbs=get;list;watch
+// +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=clusterhealthchecksprojects/status,verbs=get;update;patch
+
+// Reconcile tries to reconcile the object
+func (r *ClusterHealthchecksProjectReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("clusterhealthchecksproject", req.Name)
+
+	var project monitoringv1alpha1.ClusterHealthchecksProject
+	if err := r.Get(ctx, req.NamespacedName, &project); err != nil {
+		log.V(0).Info("unable to fetch ClusterHealthchecksProject from k8s")
+		return ctrl.Result{}, ignoreNotFound(err)
+	}
+	log.V(1).Info("fetched ClusterHealthchecksProject from k8s")
+
+	if !project.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	if updateProjectStatus(ctx, uncachedReader(r.Client, r.APIReader), r.Clients, r.NewAPI, r.Poller, r.Clock, log, project.Spec, project.Spec.APIKeySecretRef.Namespace, false, project.Generation, &project.Status) {
+		if err := r.Status().Update(ctx, &project); err != nil {
+			log.Error(err, "unable to update ClusterHealthchecksProject status")
+			return ctrl.Result{}, err
+		}
+		log.V(1).Info("updated the ClusterHealthchecksProject status")
+	}
+
+	return ctrl.Result{RequeueAfter: r.ReconcileInterval}, nil
+}
+
+// SetupWithManager hooks up the controller/reconciler
+func (r *ClusterHealthchecksProjectReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&monitoringv1alpha1.ClusterHealthchecksProject{}).
+		Complete(r)
+}
+
+// updateProjectStatus checks the connectivity of a project and counts its checks, as last polled
+// when the poller has them. A namespaced project has to be allowed by the ProjectPolicy of clients.
+// Returns true when the status changed.
+func updateProjectStatus(ctx context.Context, c client.Reader, clients *ClientCache, factory APIFactory, poller *StatusPoller, clock Clock, log logr.Logger, spec monitoringv1alpha1.HealthchecksProjectSpec, namespace string, namespaced bool, generation int64, status *monitoringv1alpha1.HealthchecksProjectStatus) bool {
+	changed := false
+
+	before, err := hashstructure.Hash(status, nil)
+	if err != nil {
+		changed = true
+	}
+
+	status.ObservedGeneration = generation
+	setCondition := func(condition monitoringv1alpha1.Condition) {
+		condition.ObservedGeneration = generation
+		condition.LastTransitionTime = *clock.Now()
+		monitoringv1alpha1.SetCondition(&status.Conditions, condition)
+	}
+
+	count := 0
+	hckio, err := projectClient(ctx, c, clients, factory, spec, namespace, namespaced)
+	if err == nil {
+		count, err = countChecks(hckio, poller)
+	}
+
+	if err != nil {
+		log.Error(err, "unable to connect to healthchecksio")
+		setCondition(monitoringv1alpha1.Condition{
+			Type:    monitoringv1alpha1.ConditionConnected,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ConnectionFailed",
+			Message: err.Error(),
+		})
+	} else {
+		checks := int32(count)
+		status.Checks = &checks
+		setCondition(monitoringv1alpha1.Condition{
+			Type:   monitoringv1alpha1.ConditionConnected,
+			Status: metav1.ConditionTrue,
+			Reason: "Connected",
+		})
+
+		if spec.CheckLimit != nil {
+			if checks >= *spec.CheckLimit {
+				setCondition(monitoringv1alpha1.Condition{
+					Type:    monitoringv1alpha1.ConditionCheckLimitReached,
+					Status:  metav1.ConditionTrue,
+					Reason:  "LimitReached",
+					Message: fmt.Sprintf("the project holds %d checks, the check limit set is %d", checks, *spec.CheckLimit),
+				})
+			} else {
+				setCondition(monitoringv1alpha1.Condition{
+					Type:    monitoringv1alpha1.ConditionCheckLimitReached,
+					Status:  metav1.ConditionFalse,
+					Reason:  "BelowLimit",
+					Message: fmt.Sprintf("the project holds %d checks, the check limit set is %d", checks, *spec.CheckLimit),
+				})
+			}
+		} else {
+			monitoringv1alpha1.RemoveCondition(&status.Conditions, monitoringv1alpha1.ConditionCheckLimitReached)
+		}
+	}
+
+	after, err := hashstructure.Hash(status, nil)
+	if err != nil {
+		changed = true
+	}
+
+	if changed == false {
+		changed = before != after
+	}
+
+	if changed {
+		status.LastUpdated = clock.Now()
+	}
+
+	return changed
+}
+
+// countChecks returns the number of checks of the api, as last polled when the poller has them. Every check of
+// the account is counted, not only the ones matching the name prefix of the project, as the limits of a
+// healthchecks.io plan apply to the whole account.
+func countChecks(api HealthchecksAPI, poller *StatusPoller) (int, error) {
+	if poller != nil {
+		if count, ok := poller.Count(api); ok {
+			return count, nil
+		}
+	}
+
+	checks, err := api.GetAll()
+	if err != nil {
+		return 0, err
+	}
+	return len(checks), nil
+}
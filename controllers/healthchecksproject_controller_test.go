@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestHealthchecksProjectController_Connected(t *testing.T) {
+	// Arrange
+	limit := int32(2)
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(
+			newProjectAPIKeySecret("team-a"),
+			&monitoringv1alpha1.HealthchecksProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "project",
+					Namespace: "team-a",
+				},
+				Spec: monitoringv1alpha1.HealthchecksProjectSpec{
+					APIKeySecretRef: newProjectSecretKeyReference(""),
+					NamePrefix:      "team-a",
+					CheckLimit:      &limit,
+				},
+			},
+		),
+		WithHckioServerResponse(200, `{
+			"checks": [
+				{"name": "team-a/default/foo", "update_url": "https://healthchecks.io/api/v1/checks/1"},
+				{"name": "not-managed", "update_url": "https://healthchecks.io/api/v1/checks/2"}
+			]
+		}`),
+	)
+	defer func() { ctx.Close() }()
+	r := newHealthchecksProjectReconciler(t, ctx)
+
+	// Act
+	res, err := r.Reconcile(NewReconcileRequest("project", "team-a"))
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(res).To(Equal(reconcile.Result{RequeueAfter: 5 * time.Minute}))
+
+	project := &monitoringv1alpha1.HealthchecksProject{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: "project", Namespace: "team-a"}, project)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(*project.Status.Checks).To(Equal(int32(2)), "every check of the account counts towards the limit")
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(project.Status.Conditions, monitoringv1alpha1.ConditionConnected)).To(BeTrue())
+	ctx.t.Expect(monitoringv1alpha1.IsConditionTrue(project.Status.Conditions, monitoringv1alpha1.ConditionCheckLimitReached)).To(BeTrue())
+}
+
+func TestHealthchecksProjectController_ConnectionFailed(t *testing.T) {
+	// Arrange
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(
+			newProjectAPIKeySecret("team-a"),
+			&monitoringv1alpha1.ClusterHealthchecksProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "project",
+				},
+				Spec: monitoringv1alpha1.HealthchecksProjectSpec{
+					APIKeySecretRef: newProjectSecretKeyReference("team-a"),
+				},
+			},
+		),
+		WithHckioServerResponse(401, `{"error": "wrong api key"}`),
+	)
+	defer func() { ctx.Close() }()
+	hr := newHealthchecksProjectReconciler(t, ctx)
+	r := &ClusterHealthchecksProjectReconciler{
+		Client:            hr.Client,
+		Log:               hr.Log,
+		Clients:           hr.Clients,
+		Clock:             hr.Clock,
+		ReconcileInterval: hr.ReconcileInterval,
+	}
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest("project", ""))
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred(), "connection failures are reported in status")
+
+	project := &monitoringv1alpha1.ClusterHealthchecksProject{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: "project"}, project)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(project.Status.Checks).To(BeNil())
+
+	connected := monitoringv1alpha1.FindCondition(project.Status.Conditions, monitoringv1alpha1.ConditionConnected)
+	ctx.t.Expect(connected).ToNot(BeNil())
+	ctx.t.Expect(connected.Status).To(Equal(metav1.ConditionFalse))
+	ctx.t.Expect(connected.Message).To(ContainSubstring("wrong api key"))
+}
+
+func TestHealthchecksProjectController_Poller(t *testing.T) {
+	// Arrange
+	server, requests := newStatusPollerServer()
+	defer server.Close()
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithHckioBaseURL(server.URL),
+		WithK8sObjects(
+			newProjectAPIKeySecret("team-a"),
+			&monitoringv1alpha1.HealthchecksProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "project",
+					Namespace: "team-a",
+				},
+				Spec: monitoringv1alpha1.HealthchecksProjectSpec{
+					APIKeySecretRef: newProjectSecretKeyReference(""),
+				},
+			},
+		),
+	)
+	defer func() { ctx.Close() }()
+	r := newHealthchecksProjectReconciler(t, ctx)
+	r.Poller = NewStatusPoller(testutil.LogrTestLogger{T: t}, time.Minute)
+
+	// Act & assert, the checks are read while the poller has not polled the project yet
+	_, err := r.Reconcile(NewReconcileRequest("project", "team-a"))
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(*requests).To(Equal([]string{"/checks/"}))
+
+	// Act & assert, the checks are counted as polled afterwards
+	r.Poller.Poll()
+	_, err = r.Reconcile(NewReconcileRequest("project", "team-a"))
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(*requests).To(Equal([]string{"/checks/", "/checks/", "/channels/"}))
+
+	project := &monitoringv1alpha1.HealthchecksProject{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: "project", Namespace: "team-a"}, project)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(*project.Status.Checks).To(Equal(int32(1)))
+}
+
+func TestCheckProject_ResolveProject(t *testing.T) {
+	// Arrange
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "team-a",
+		},
+		Spec: monitoringv1alpha1.CheckSpec{
+			ProjectRef: &monitoringv1alpha1.ProjectReference{Name: "project"},
+		},
+	}
+	ctx := NewCheckReconcilerTest(
+		t,
+		WithK8sObjects(
+			check,
+			newProjectAPIKeySecret("team-a"),
+			&monitoringv1alpha1.HealthchecksProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "project",
+					Namespace: "team-a",
+				},
+				Spec: monitoringv1alpha1.HealthchecksProjectSpec{
+					APIKeySecretRef: newProjectSecretKeyReference(""),
+					BaseURL:         "https://hc.example.com/api/v1",
+					NamePrefix:      "team-a",
+					DefaultTags:     []string{"team-a"},
+					DefaultChannels: []string{"email"},
+				},
+			},
+			&monitoringv1alpha1.ClusterHealthchecksProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "project",
+				},
+				Spec: monitoringv1alpha1.HealthchecksProjectSpec{
+					APIKeySecretRef: newProjectSecretKeyReference(""),
+				},
+			},
+		),
+	)
+	defer func() { ctx.Close() }()
+
+	// Act
+	_, err := ctx.Reconciler.resolveProject(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).To(MatchError("the base url https://hc.example.com/api/v1 is not allowed for a HealthchecksProject"))
+
+	// Act
+	ctx.Reconciler.Clients.ProjectPolicy.AllowedBaseURLs = []string{"https://hc.example.com/api/v1"}
+	p, err := ctx.Reconciler.resolveProject(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(p.client.(*HealthchecksioAPI).client.APIKey).To(Equal("project-api-key"))
+	ctx.t.Expect(p.client.BaseURL()).To(Equal("https://hc.example.com/api/v1"))
+
+	desired := p.applyDefaults(*check)
+	ctx.t.Expect(desired.Spec.Tags).To(Equal([]string{"team-a"}))
+	ctx.t.Expect(desired.Spec.Channels).To(Equal([]string{"email"}))
+	ctx.t.Expect(check.Spec.Tags).To(BeEmpty(), "the original check is left untouched")
+	ctx.t.Expect(p.convertToHealthcheck(desired).Name).To(Equal("team-a/team-a/foo"))
+
+	// Act
+	project := &monitoringv1alpha1.HealthchecksProject{}
+	ctx.t.Expect(ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "project", Namespace: "team-a"}, project)).To(Succeed())
+	project.Spec.InsecureSkipVerify = true
+	ctx.t.Expect(ctx.Reconciler.Client.Update(context.TODO(), project)).To(Succeed())
+	_, err = ctx.Reconciler.resolveProject(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).To(MatchError("insecureSkipVerify is not allowed for a HealthchecksProject"))
+
+	// Act
+	ctx.Reconciler.Clients.ProjectPolicy.AllowInsecureSkipVerify = true
+	_, err = ctx.Reconciler.resolveProject(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Act
+	check.Spec.ProjectRef.Kind = "ClusterHealthchecksProject"
+	_, err = ctx.Reconciler.resolveProject(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).To(HaveOccurred(), "the secret namespace of a cluster project must be set")
+}
+
+func newHealthchecksProjectReconciler(t *testing.T, ctx *CheckReconcilerTestContext) *HealthchecksProjectReconciler {
+	return &HealthchecksProjectReconciler{
+		Client:            ctx.Reconciler.Client,
+		Log:               testutil.LogrTestLogger{T: t},
+		Clients:           ctx.Reconciler.Clients,
+		Clock:             ctx.Reconciler.Clock,
+		ReconcileInterval: 5 * time.Minute,
+	}
+}
+
+func newProjectAPIKeySecret(namespace string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "project-api-key",
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			"api-key": []byte("project-api-key"),
+		},
+	}
+}
+
+func newProjectSecretKeyReference(namespace string) monitoringv1alpha1.ProjectSecretKeyReference {
+	return monitoringv1alpha1.ProjectSecretKeyReference{
+		SecretKeyReference: monitoringv1alpha1.SecretKeyReference{
+			Name: "project-api-key",
+			Key:  "api-key",
+		},
+		Namespace: namespace,
+	}
+}
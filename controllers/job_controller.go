@@ -0,0 +1,456 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// AnnotationPings records the last ping sent for a Job, per check, as a comma separated list of check=ping.
+// A ping is recorded before it is sent, and removed again when sending fails, so it is never sent twice.
+const AnnotationPings = "healthchecks.io/pings"
+
+// Pings sent for a Job
+const (
+	jobPingStart   = "start"
+	jobPingSuccess = "success"
+	jobPingFail    = "fail"
+)
+
+// Reasons used for events recorded on a Check
+const (
+	EventReasonPingSent   = "PingSent"
+	EventReasonPingFailed = "PingFailed"
+)
+
+// maxFailureLogBytes bounds the size of the logs sent with a fail ping
+const maxFailureLogBytes = 10000
+
+// maxFailureLogLines bounds the number of log lines read from a failed container
+const maxFailureLogLines = int64(100)
+
+// jobStartPollInterval is how often the pods of an active Job are checked for one that started running
+const jobStartPollInterval = 10 * time.Second
+
+// PodLogReader reads the logs of a container of a pod
+type PodLogReader func(namespace, name string, opts *corev1.PodLogOptions) ([]byte, error)
+
+// NewPodLogReader creates a PodLogReader reading logs through clientset
+func NewPodLogReader(clientset kubernetes.Interface) PodLogReader {
+	return func(namespace, name string, opts *corev1.PodLogOptions) ([]byte, error) {
+		return clientset.CoreV1().Pods(namespace).GetLogs(name, opts).Do().Raw()
+	}
+}
+
+// JobReconciler sends pings to the checks selecting a Job when the Job starts, completes or fails
+type JobReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Pinger   *Pinger
+	PodLogs  PodLogReader
+
+	// APIReader lists the pods of a Job from the API server, pods are not cached
+	APIReader client.Reader
+}
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+
+// Reconcile tries to reconcile the object
+func (r *JobReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("job", req.NamespacedName)
+
+	var job batchv1.Job
+	if err := r.Get(ctx, req.NamespacedName, &job); err != nil {
+		log.V(1).Info("unable to fetch Job from k8s")
+		return ctrl.Result{}, ignoreNotFound(err)
+	}
+
+	ping := jobPing(job)
+	if ping == "" || !job.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	checks, err := r.selectingChecks(ctx, job)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	sent := parsePings(job.Annotations[AnnotationPings])
+	pending := make([]*monitoringv1alpha1.Check, 0)
+	waiting := false
+	var running *bool
+	for i := range checks {
+		check := &checks[i]
+		last := sent[check.Name]
+		// a start ping is not sent for a job that already finished, it would report a wrong duration
+		if check.Status.PingURL == "" || last == ping || (ping == jobPingStart && last != "") {
+			continue
+		}
+		lifecycle := check.Spec.JobSelector != nil && jobSelected(*check.Spec.JobSelector, job)
+		if !lifecycle && ping != jobPingFail {
+			continue
+		}
+		// the start ping is sent once a pod of the job is running, not when the job is created
+		if ping == jobPingStart {
+			if running == nil {
+				started := r.podStarted(ctx, job)
+				running = &started
+			}
+			if !*running {
+				waiting = true
+				continue
+			}
+		}
+		// a job that finished before the check was created, e.g. one kept in the history of a
+		// CronJob, is not reported, a fail ping would raise an alert for an old failure
+		if finished := jobFinishedAt(job); last == "" && finished != nil && finished.Before(&check.CreationTimestamp) {
+			continue
+		}
+
+		pending = append(pending, check)
+	}
+
+	if len(pending) > 0 {
+		if err := r.sendPings(ctx, job, ping, pending, sent); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if waiting {
+		log.V(1).Info("no pod of the Job is running yet, delaying the start ping")
+		return ctrl.Result{RequeueAfter: jobStartPollInterval}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// sendPings sends the ping for the job to the checks. The pings are recorded on the job before they are
+// sent, so a ping is not sent twice when recording fails, and the pings failing to send are removed again
+// to be retried.
+func (r *JobReconciler) sendPings(ctx context.Context, job batchv1.Job, ping string, checks []*monitoringv1alpha1.Check, sent map[string]string) error {
+	log := r.Log.WithValues("job", types.NamespacedName{Name: job.Name, Namespace: job.Namespace})
+
+	previous := make(map[string]string, len(checks))
+	for _, check := range checks {
+		previous[check.Name] = sent[check.Name]
+		sent[check.Name] = ping
+	}
+	if err := r.recordPings(ctx, &job, sent); err != nil {
+		log.Error(err, "unable to record pings on Job")
+		return err
+	}
+
+	var pingErr error
+	var failureLogs []byte
+	for _, check := range checks {
+		logs := check.Spec.FailureLogsFrom != nil && jobSelected(*check.Spec.FailureLogsFrom, job)
+
+		var body []byte
+		if logs && ping == jobPingFail {
+			if failureLogs == nil {
+				failureLogs = r.failureLogs(ctx, job)
+			}
+			body = failureLogs
+		}
+
+		url := jobPingURL(check.Status.PingURL, ping)
+		opts, err := pingOptions(ctx, r.Client, check)
+		if err == nil {
+			err = r.Pinger.Ping(opts, url, body)
+		}
+		if err != nil {
+			log.Error(err, "unable to send ping", "check", check.Name, "ping", ping)
+			r.Recorder.Eventf(check, corev1.EventTypeWarning, EventReasonPingFailed, "Failed to send %s ping for Job %s: %s", ping, job.Name, err)
+			// the failed ping is retried
+			if previous[check.Name] == "" {
+				delete(sent, check.Name)
+			} else {
+				sent[check.Name] = previous[check.Name]
+			}
+			pingErr = err
+			continue
+		}
+		log.V(0).Info(fmt.Sprintf("sent %s ping", ping), "check", check.Name)
+		r.Recorder.Eventf(check, corev1.EventTypeNormal, EventReasonPingSent, "Sent %s ping for Job %s", ping, job.Name)
+	}
+
+	if pingErr != nil {
+		if err := r.recordPings(ctx, &job, sent); err != nil {
+			log.Error(err, "unable to remove failed pings from Job")
+			return err
+		}
+	}
+
+	return pingErr
+}
+
+// recordPings records the pings on the job. Only the annotation is patched, the Job is owned by its
+// user and the Job controller.
+func (r *JobReconciler) recordPings(ctx context.Context, job *batchv1.Job, pings map[string]string) error {
+	patch := client.MergeFrom(job.DeepCopy())
+	if job.Annotations == nil {
+		job.Annotations = make(map[string]string)
+	}
+	if len(pings) == 0 {
+		delete(job.Annotations, AnnotationPings)
+	} else {
+		job.Annotations[AnnotationPings] = formatPings(pings)
+	}
+	return r.Patch(ctx, job, patch)
+}
+
+// podStarted returns true when a container of a pod of the job is running or has terminated.
+// Failing to list the pods is only logged, the start ping is sent once they can be listed.
+func (r *JobReconciler) podStarted(ctx context.Context, job batchv1.Job) bool {
+	for _, pod := range r.jobPods(ctx, job) {
+		for _, c := range pod.Status.ContainerStatuses {
+			if c.State.Running != nil || c.State.Terminated != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jobPods returns the pods of the job, read from the API server as pods are not cached.
+// Pods of a previous Job with the same name are left out.
+func (r *JobReconciler) jobPods(ctx context.Context, job batchv1.Job) []corev1.Pod {
+	var pods corev1.PodList
+	if err := uncachedReader(r.Client, r.APIReader).List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		r.Log.Error(err, "unable to list pods of Job", "job", fmt.Sprintf("%s/%s", job.Namespace, job.Name))
+		return nil
+	}
+
+	owned := make([]corev1.Pod, 0, len(pods.Items))
+	for _, p := range pods.Items {
+		if metav1.IsControlledBy(&p, &job) {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+// selectingChecks returns the checks in the namespace of the job with a job selector matching the job
+func (r *JobReconciler) selectingChecks(ctx context.Context, job batchv1.Job) ([]monitoringv1alpha1.Check, error) {
+	var checks monitoringv1alpha1.CheckList
+	if err := r.List(ctx, &checks, client.InNamespace(job.Namespace)); err != nil {
+		return nil, err
+	}
+
+	selecting := make([]monitoringv1alpha1.Check, 0)
+	for _, c := range checks.Items {
+		if (c.Spec.JobSelector != nil && jobSelected(*c.Spec.JobSelector, job)) ||
+			(c.Spec.FailureLogsFrom != nil && jobSelected(*c.Spec.FailureLogsFrom, job)) {
+			selecting = append(selecting, c)
+		}
+	}
+
+	return selecting, nil
+}
+
+// failureLogs returns the termination message, or the tail of the logs, of the failed container
+// of the most recent failed pod of the job. Failing to read them is only logged as the fail
+// ping is sent without logs.
+func (r *JobReconciler) failureLogs(ctx context.Context, job batchv1.Job) []byte {
+	log := r.Log.WithValues("job", fmt.Sprintf("%s/%s", job.Namespace, job.Name))
+
+	pod, container := failedContainer(r.jobPods(ctx, job))
+	if pod == nil {
+		log.V(1).Info("no failed container found for Job")
+		return []byte{}
+	}
+
+	if msg := container.State.Terminated.Message; msg != "" {
+		return tailBytes([]byte(msg), maxFailureLogBytes)
+	}
+
+	tailLines := maxFailureLogLines
+	logs, err := r.PodLogs(pod.Namespace, pod.Name, &corev1.PodLogOptions{
+		Container: container.Name,
+		TailLines: &tailLines,
+	})
+	if err != nil {
+		log.Error(err, "unable to read logs of failed container", "pod", pod.Name, "container", container.Name)
+		return []byte{}
+	}
+
+	return tailBytes(logs, maxFailureLogBytes)
+}
+
+// failedContainer returns the most recently started pod with a container that terminated with a non-zero exit code
+func failedContainer(pods []corev1.Pod) (*corev1.Pod, *corev1.ContainerStatus) {
+	var pod *corev1.Pod
+	var container *corev1.ContainerStatus
+	for i := range pods {
+		p := &pods[i]
+		if pod != nil && p.Status.StartTime != nil && pod.Status.StartTime != nil && p.Status.StartTime.Before(pod.Status.StartTime) {
+			continue
+		}
+		for j := range p.Status.ContainerStatuses {
+			c := &p.Status.ContainerStatuses[j]
+			if c.State.Terminated != nil && c.State.Terminated.ExitCode != 0 {
+				pod, container = p, c
+				break
+			}
+		}
+	}
+	return pod, container
+}
+
+// tailBytes returns at most the last n bytes of b
+func tailBytes(b []byte, n int) []byte {
+	if len(b) <= n {
+		return b
+	}
+	return b[len(b)-n:]
+}
+
+// jobSelected returns true when the job is selected by the selector
+func jobSelected(selector monitoringv1alpha1.JobSelector, job batchv1.Job) bool {
+	if selector.CronJobName != "" {
+		owner := metav1.GetControllerOf(&job)
+		return owner != nil && owner.Kind == "CronJob" && owner.Name == selector.CronJobName
+	}
+
+	if selector.Selector != nil {
+		s, err := metav1.LabelSelectorAsSelector(selector.Selector)
+		return err == nil && !s.Empty() && s.Matches(labels.Set(job.Labels))
+	}
+
+	return false
+}
+
+// jobPing returns the ping reflecting the state of the job, or an empty string when the job has not started
+func jobPing(job batchv1.Job) string {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobFailed:
+			return jobPingFail
+		case batchv1.JobComplete:
+			return jobPingSuccess
+		}
+	}
+
+	if job.Status.StartTime != nil {
+		return jobPingStart
+	}
+
+	return ""
+}
+
+// jobFinishedAt returns the time the job completed or failed, or nil when it has not finished or the time is unknown
+func jobFinishedAt(job batchv1.Job) *metav1.Time {
+	for _, c := range job.Status.Conditions {
+		if c.Status == corev1.ConditionTrue && (c.Type == batchv1.JobFailed || c.Type == batchv1.JobComplete) && !c.LastTransitionTime.IsZero() {
+			return &c.LastTransitionTime
+		}
+	}
+
+	if job.Status.CompletionTime != nil && !job.Status.CompletionTime.IsZero() {
+		return job.Status.CompletionTime
+	}
+
+	return nil
+}
+
+// jobPingURL returns the URL of the ping
+func jobPingURL(pingURL, ping string) string {
+	switch ping {
+	case jobPingStart:
+		return pingURL + "/start"
+	case jobPingFail:
+		return pingURL + "/fail"
+	}
+	return pingURL
+}
+
+func parsePings(value string) map[string]string {
+	pings := make(map[string]string)
+	for _, item := range SplitList(value) {
+		p := strings.SplitN(item, "=", 2)
+		if len(p) == 2 {
+			pings[p[0]] = p[1]
+		}
+	}
+	return pings
+}
+
+func formatPings(pings map[string]string) string {
+	items := make([]string, 0, len(pings))
+	for check, ping := range pings {
+		items = append(items, check+"="+ping)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+// selectedJobs returns requests for the Jobs selected by a Check, so they are pinged once the Check has a ping url
+func (r *JobReconciler) selectedJobs(obj handler.MapObject) []reconcile.Request {
+	check, ok := obj.Object.(*monitoringv1alpha1.Check)
+	if !ok || (check.Spec.JobSelector == nil && check.Spec.FailureLogsFrom == nil) {
+		return nil
+	}
+
+	var jobs batchv1.JobList
+	if err := r.List(context.Background(), &jobs, client.InNamespace(check.Namespace)); err != nil {
+		r.Log.Error(err, "unable to list Jobs selected by Check", "check", fmt.Sprintf("%s/%s", check.Namespace, check.Name))
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0)
+	for _, job := range jobs.Items {
+		if (check.Spec.JobSelector != nil && jobSelected(*check.Spec.JobSelector, job)) ||
+			(check.Spec.FailureLogsFrom != nil && jobSelected(*check.Spec.FailureLogsFrom, job)) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: job.Name, Namespace: job.Namespace}})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager hooks up the controller/reconciler
+func (r *JobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &monitoringv1alpha1.Check{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.selectedJobs)}).
+		Complete(r)
+}
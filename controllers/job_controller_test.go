@@ -0,0 +1,462 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestJobController_JobSelected(t *testing.T) {
+	g := NewGomegaWithT(t)
+	isController := true
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"app": "backup"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "CronJob", Name: "backup", Controller: &isController},
+			},
+		},
+	}
+
+	g.Expect(jobSelected(monitoringv1alpha1.JobSelector{CronJobName: "backup"}, job)).To(BeTrue())
+	g.Expect(jobSelected(monitoringv1alpha1.JobSelector{CronJobName: "restore"}, job)).To(BeFalse())
+	g.Expect(jobSelected(monitoringv1alpha1.JobSelector{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}}}, job)).To(BeTrue())
+	g.Expect(jobSelected(monitoringv1alpha1.JobSelector{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "restore"}}}, job)).To(BeFalse())
+	g.Expect(jobSelected(monitoringv1alpha1.JobSelector{Selector: &metav1.LabelSelector{}}, job)).To(BeFalse())
+}
+
+func TestJobController_JobPing(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := metav1.Now()
+
+	g.Expect(jobPing(batchv1.Job{})).To(Equal(""))
+	g.Expect(jobPing(batchv1.Job{Status: batchv1.JobStatus{StartTime: &now}})).To(Equal(jobPingStart))
+	g.Expect(jobPing(newJobWithCondition(batchv1.JobComplete, corev1.ConditionTrue))).To(Equal(jobPingSuccess))
+	g.Expect(jobPing(newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue))).To(Equal(jobPingFail))
+	g.Expect(jobPing(newJobWithCondition(batchv1.JobFailed, corev1.ConditionFalse))).To(Equal(jobPingStart))
+}
+
+func TestJobController_ParseAndFormatPings(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(parsePings("")).To(BeEmpty())
+	g.Expect(parsePings("b=success,a=start,invalid")).To(Equal(map[string]string{"a": "start", "b": "success"}))
+	g.Expect(formatPings(map[string]string{"b": "success", "a": "start"})).To(Equal("a=start,b=success"))
+}
+
+func TestJobController_SendPings(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pings := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		pings = append(pings, req.URL.Path)
+	}))
+	defer server.Close()
+
+	job := newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue)
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: job.Namespace},
+		Spec: monitoringv1alpha1.CheckSpec{
+			JobSelector: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: server.URL + "/c1"},
+	}
+	other := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: job.Namespace},
+		Status:     monitoringv1alpha1.CheckStatus{PingURL: server.URL + "/c2"},
+	}
+	r := newJobReconciler(t, &job, check, other)
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Assert, the fail ping is only sent once
+	g.Expect(pings).To(Equal([]string{"/c1/fail"}))
+
+	actual := &batchv1.Job{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, actual)).To(Succeed())
+	g.Expect(actual.Annotations[AnnotationPings]).To(Equal("backup=fail"))
+	g.Expect(<-r.Recorder.(*record.FakeRecorder).Events).To(Equal("Normal PingSent Sent fail ping for Job backup-1234"))
+}
+
+func TestJobController_RecordPingsFailed(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pings := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		pings = append(pings, req.URL.Path)
+		if len(pings) == 1 {
+			res.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	job := newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue)
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: job.Namespace},
+		Spec: monitoringv1alpha1.CheckSpec{
+			JobSelector: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: server.URL + "/c1"},
+	}
+	// a ping recorded for another check keeps the annotation, the fake client does not remove it when patched
+	job.Annotations = map[string]string{AnnotationPings: "other=fail"}
+	r := newJobReconciler(t, &job, check)
+	r.Client = &failingPatchClient{Client: r.Client, failures: 1}
+
+	// Act & assert, no ping is sent before it is recorded
+	_, err := r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(pings).To(BeEmpty())
+
+	// Act & assert, the ping failing to send is removed again and retried
+	_, err = r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).To(HaveOccurred())
+	_, err = r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pings).To(Equal([]string{"/c1/fail", "/c1/fail"}))
+
+	// Act & assert, the ping recorded before it was sent is not sent again
+	_, err = r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pings).To(HaveLen(2))
+	actual := &batchv1.Job{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, actual)).To(Succeed())
+	g.Expect(actual.Annotations[AnnotationPings]).To(Equal("backup=fail,other=fail"))
+}
+
+func TestJobController_StartPingOnceRunning(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pings := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		pings = append(pings, req.URL.Path)
+	}))
+	defer server.Close()
+
+	now := metav1.Now()
+	job := newJobWithCondition(batchv1.JobFailed, corev1.ConditionFalse)
+	job.UID = "1234"
+	job.Status.StartTime = &now
+	isController := true
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "backup-1234-abcde",
+			Namespace:       job.Namespace,
+			Labels:          map[string]string{"job-name": job.Name},
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: job.Name, UID: job.UID, Controller: &isController}},
+		},
+		Status: corev1.PodStatus{
+			StartTime:         &now,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "main", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}}},
+		},
+	}
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: job.Namespace},
+		Spec: monitoringv1alpha1.CheckSpec{
+			JobSelector: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: server.URL + "/c1"},
+	}
+	r := newJobReconciler(t, &job, &pod, check)
+
+	// Act & assert, the start ping waits for a running pod
+	res, err := r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(Equal(jobStartPollInterval))
+	g.Expect(pings).To(BeEmpty())
+
+	// Act & assert, the start ping is sent once the pod runs
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: now}}
+	g.Expect(r.Update(context.TODO(), &pod)).To(Succeed())
+	res, err = r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(BeZero())
+	g.Expect(pings).To(Equal([]string{"/c1/start"}))
+
+	actual := &batchv1.Job{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, actual)).To(Succeed())
+	g.Expect(actual.Annotations[AnnotationPings]).To(Equal("backup=start"))
+}
+
+func TestJobController_SelectedJobs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	selected := newJobWithCondition(batchv1.JobComplete, corev1.ConditionTrue)
+	other := newJobWithCondition(batchv1.JobComplete, corev1.ConditionTrue)
+	other.Name = "restore-1234"
+	other.Labels = map[string]string{"app": "restore"}
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: selected.Namespace},
+		Spec: monitoringv1alpha1.CheckSpec{
+			JobSelector: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+	}
+	r := newJobReconciler(t, &selected, &other, check)
+
+	g.Expect(r.selectedJobs(handler.MapObject{Meta: check, Object: check})).To(Equal([]reconcile.Request{NewReconcileRequest(selected.Name, selected.Namespace)}))
+	g.Expect(r.selectedJobs(handler.MapObject{Meta: &other, Object: &monitoringv1alpha1.Check{}})).To(BeEmpty())
+}
+
+func TestJobController_JobFinishedAt(t *testing.T) {
+	g := NewGomegaWithT(t)
+	now := metav1.Now()
+
+	failed := newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue)
+	failed.Status.Conditions[0].LastTransitionTime = now
+	completed := newJobWithCondition(batchv1.JobComplete, corev1.ConditionTrue)
+	completed.Status.CompletionTime = &now
+
+	g.Expect(jobFinishedAt(failed)).To(Equal(&now))
+	g.Expect(jobFinishedAt(completed)).To(Equal(&now))
+	g.Expect(jobFinishedAt(newJobWithCondition(batchv1.JobFailed, corev1.ConditionFalse))).To(BeNil())
+	g.Expect(jobFinishedAt(newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue))).To(BeNil())
+}
+
+func TestJobController_SkipJobsFinishedBeforeCheck(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pings := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		pings = append(pings, req.URL.Path)
+	}))
+	defer server.Close()
+
+	created := metav1.NewTime(time.Now().Truncate(time.Second))
+	old := newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue)
+	old.Status.Conditions[0].LastTransitionTime = metav1.NewTime(created.Add(-time.Hour))
+	recent := newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue)
+	recent.Name = "backup-5678"
+	recent.Status.Conditions[0].LastTransitionTime = metav1.NewTime(created.Add(time.Minute))
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: old.Namespace, CreationTimestamp: created},
+		Spec: monitoringv1alpha1.CheckSpec{
+			JobSelector: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: server.URL + "/c1"},
+	}
+	r := newJobReconciler(t, &old, &recent, check)
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest(old.Name, old.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = r.Reconcile(NewReconcileRequest(recent.Name, recent.Namespace))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Assert, only the job that failed after the check was created is reported
+	g.Expect(pings).To(Equal([]string{"/c1/fail"}))
+
+	actual := &batchv1.Job{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: old.Name, Namespace: old.Namespace}, actual)).To(Succeed())
+	g.Expect(actual.Annotations).ToNot(HaveKey(AnnotationPings))
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: recent.Name, Namespace: recent.Namespace}, actual)).To(Succeed())
+	g.Expect(actual.Annotations[AnnotationPings]).To(Equal("backup=fail"))
+}
+
+func TestJobController_FailedContainer(t *testing.T) {
+	g := NewGomegaWithT(t)
+	earlier := metav1.NewTime(time.Now().Add(-time.Minute))
+	later := metav1.Now()
+
+	pod, container := failedContainer([]corev1.Pod{
+		newPodWithExitCode("first", &earlier, 1),
+		newPodWithExitCode("second", &later, 2),
+		newPodWithExitCode("succeeded", &later, 0),
+	})
+	g.Expect(pod.Name).To(Equal("second"))
+	g.Expect(container.State.Terminated.ExitCode).To(Equal(int32(2)))
+
+	pod, container = failedContainer([]corev1.Pod{newPodWithExitCode("succeeded", &later, 0)})
+	g.Expect(pod).To(BeNil())
+	g.Expect(container).To(BeNil())
+}
+
+func TestJobController_TailBytes(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(tailBytes([]byte("foo"), 10)).To(Equal([]byte("foo")))
+	g.Expect(tailBytes([]byte("foobar"), 3)).To(Equal([]byte("bar")))
+}
+
+func TestJobController_SendFailureLogs(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pings := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		b, _ := ioutil.ReadAll(req.Body)
+		pings = append(pings, req.URL.Path+" "+string(b))
+	}))
+	defer server.Close()
+
+	job := newJobWithCondition(batchv1.JobFailed, corev1.ConditionTrue)
+	job.UID = "1234"
+	now := metav1.Now()
+	pod := newPodWithExitCode("backup-1234-abcde", &now, 1)
+	pod.Labels = map[string]string{"job-name": job.Name}
+	isController := true
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: job.Name, UID: job.UID, Controller: &isController}}
+	previous := newPodWithExitCode("backup-1234-fghij", &now, 2)
+	previous.Labels = map[string]string{"job-name": job.Name}
+	previous.OwnerReferences = []metav1.OwnerReference{{Kind: "Job", Name: job.Name, UID: "5678", Controller: &isController}}
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: job.Namespace},
+		Spec: monitoringv1alpha1.CheckSpec{
+			FailureLogsFrom: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: server.URL + "/c1"},
+	}
+	r := newJobReconciler(t, &job, &pod, &previous, check)
+	r.PodLogs = func(namespace, name string, opts *corev1.PodLogOptions) ([]byte, error) {
+		return []byte(fmt.Sprintf("logs of %s/%s/%s, %d lines", namespace, name, opts.Container, *opts.TailLines)), nil
+	}
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+
+	// Assert
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(pings).To(Equal([]string{"/c1/fail logs of default/backup-1234-abcde/main, 100 lines"}))
+}
+
+func TestJobController_SendFailureLogs_OnlyOnFailure(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	job := newJobWithCondition(batchv1.JobComplete, corev1.ConditionTrue)
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: job.Namespace},
+		Spec: monitoringv1alpha1.CheckSpec{
+			FailureLogsFrom: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: "http://127.0.0.1:0/c1"},
+	}
+	r := newJobReconciler(t, &job, check)
+
+	// Act, sending a ping would fail as nothing listens on the ping url
+	_, err := r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+
+	// Assert
+	g.Expect(err).ToNot(HaveOccurred())
+}
+
+func TestJobController_SendFailureLogs_NoStartWait(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	job := newJobWithCondition(batchv1.JobFailed, corev1.ConditionFalse)
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: job.Namespace},
+		Spec: monitoringv1alpha1.CheckSpec{
+			FailureLogsFrom: &monitoringv1alpha1.JobSelector{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "backup"}},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: "http://127.0.0.1:0/c1"},
+	}
+	r := newJobReconciler(t, &job, check)
+
+	// Act, no pod of the job is running
+	res, err := r.Reconcile(NewReconcileRequest(job.Name, job.Namespace))
+
+	// Assert, the check only gets the fail ping, the job is not requeued to wait for its pods
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.RequeueAfter).To(BeZero())
+}
+
+func newPodWithExitCode(name string, startTime *metav1.Time, exitCode int32) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			StartTime: startTime,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "main",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newJobWithCondition(conditionType batchv1.JobConditionType, status corev1.ConditionStatus) batchv1.Job {
+	now := metav1.Now()
+	return batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "backup-1234",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "backup"},
+		},
+		Status: batchv1.JobStatus{
+			StartTime: &now,
+			Conditions: []batchv1.JobCondition{
+				{Type: conditionType, Status: status},
+			},
+		},
+	}
+}
+
+// failingPatchClient fails the next failures patches
+type failingPatchClient struct {
+	client.Client
+	failures int
+}
+
+func (c *failingPatchClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if c.failures > 0 {
+		c.failures--
+		return fmt.Errorf("patch failed")
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func newJobReconciler(t *testing.T, objs ...runtime.Object) *JobReconciler {
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{}, &monitoringv1alpha1.CheckList{})
+
+	return &JobReconciler{
+		Client:   fake.NewFakeClientWithScheme(s, objs...),
+		Log:      testutil.LogrTestLogger{T: t},
+		Recorder: record.NewFakeRecorder(100),
+		Pinger:   NewPinger(&http.Client{}),
+	}
+}
@@ -0,0 +1,93 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// LeaderLabel is set to "true" on the pod of the leading replica of the operator
+const LeaderLabel = "healthchecks.io/leader"
+
+// leaderLabelRetryInterval is the interval labeling the pod of the leader is retried at
+const leaderLabelRetryInterval = 5 * time.Second
+
+var _ manager.Runnable = &LeaderLabeler{}
+var _ manager.LeaderElectionRunnable = &LeaderLabeler{}
+
+// LeaderLabeler labels the pod of the operator with LeaderLabel while it leads, so a Service selecting
+// the label routes to the leader only. A replica losing the lease exits, its pod is either removed or
+// restarted, clearing the label through Clear before the manager starts.
+type LeaderLabeler struct {
+	Client    client.Client
+	Log       logr.Logger
+	Name      string
+	Namespace string
+}
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=patch
+
+// Start labels the pod of the operator once it leads, retrying until it succeeds or stop is closed
+func (l *LeaderLabeler) Start(stop <-chan struct{}) error {
+	err := wait.PollImmediateUntil(leaderLabelRetryInterval, func() (bool, error) {
+		if err := l.patch(context.Background(), "true"); err != nil {
+			l.Log.Error(err, "unable to label the pod of the leader")
+			return false, nil
+		}
+		return true, nil
+	}, stop)
+	if err == wait.ErrWaitTimeout {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	l.Log.V(0).Info("labeled the pod of the leader", "pod", l.Name, "label", LeaderLabel)
+	return nil
+}
+
+// NeedLeaderElection ensures only the pod of the leading replica is labeled
+func (l *LeaderLabeler) NeedLeaderElection() bool {
+	return true
+}
+
+// Clear removes the label from the pod of the operator, left behind when it led before a restart
+func (l *LeaderLabeler) Clear(ctx context.Context) error {
+	return l.patch(ctx, "")
+}
+
+// patch sets the label of the pod to value, removing it when empty
+func (l *LeaderLabeler) patch(ctx context.Context, value string) error {
+	label := "null"
+	if value != "" {
+		label = fmt.Sprintf("%q", value)
+	}
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: l.Name, Namespace: l.Namespace}}
+	patch := client.ConstantPatch(types.MergePatchType, []byte(fmt.Sprintf(`{"metadata":{"labels":{%q:%s}}}`, LeaderLabel, label)))
+	return l.Client.Patch(ctx, pod, patch)
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestLeaderLabeler(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "operator",
+			Namespace: "system",
+			Labels:    map[string]string{"control-plane": "controller-manager"},
+		},
+	}
+	c := &patchRecordingClient{Client: fake.NewFakeClientWithScheme(scheme.Scheme, pod)}
+	l := &LeaderLabeler{Client: c, Log: testutil.LogrTestLogger{T: t}, Name: "operator", Namespace: "system"}
+
+	// Act & assert, the pod is labeled once leading
+	g.Expect(l.Start(make(chan struct{}))).To(Succeed())
+	labeled := &corev1.Pod{}
+	g.Expect(c.Get(context.TODO(), types.NamespacedName{Name: "operator", Namespace: "system"}, labeled)).To(Succeed())
+	g.Expect(labeled.Labels).To(HaveKeyWithValue(LeaderLabel, "true"))
+	g.Expect(labeled.Labels).To(HaveKeyWithValue("control-plane", "controller-manager"))
+	g.Expect(l.NeedLeaderElection()).To(BeTrue())
+
+	// Act & assert, the label left behind by a previous run is removed
+	g.Expect(l.Clear(context.TODO())).To(Succeed())
+	g.Expect(c.patches).To(Equal([]string{
+		`{"metadata":{"labels":{"healthchecks.io/leader":"true"}}}`,
+		`{"metadata":{"labels":{"healthchecks.io/leader":null}}}`,
+	}))
+}
+
+// patchRecordingClient records the data of the patches sent through it
+type patchRecordingClient struct {
+	client.Client
+	patches []string
+}
+
+func (c *patchRecordingClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	c.patches = append(c.patches, string(data))
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
@@ -0,0 +1,231 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// checkStatuses are the statuses a check in healthchecks.io can have
+var checkStatuses = []string{"new", "up", "grace", "down", "paused"}
+
+// collectTimeout bounds listing the Checks when metrics are scraped
+const collectTimeout = 10 * time.Second
+
+var (
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "healthchecksio_api_request_duration_seconds",
+		Help:    "Latency of requests to the healthchecks.io API by endpoint, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint", "method", "code"})
+
+	apiRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "healthchecksio_api_request_errors_total",
+		Help: "Requests to the healthchecks.io API that failed or returned an error status, by endpoint, method and status code.",
+	}, []string{"endpoint", "method", "code"})
+
+	channelsUnresolved = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "healthchecksio_check_unresolved_channels",
+		Help: "Number of channels of a Check not matching a channel in healthchecks.io.",
+	}, []string{"namespace", "name"})
+)
+
+var (
+	checkStatusDesc = prometheus.NewDesc(
+		"healthchecksio_check_status",
+		"Status of the check of a Check in healthchecks.io, 1 for the current status.",
+		[]string{"namespace", "name", "tags", "status"}, nil,
+	)
+	checkPingsDesc = prometheus.NewDesc(
+		"healthchecksio_check_pings_total",
+		"Number of pings received by the check of a Check.",
+		[]string{"namespace", "name"}, nil,
+	)
+	checkLastPingAgeDesc = prometheus.NewDesc(
+		"healthchecksio_check_last_ping_age_seconds",
+		"Seconds since the check of a Check last received a ping.",
+		[]string{"namespace", "name"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(apiRequestDuration, apiRequestErrors, channelsUnresolved)
+}
+
+var _ prometheus.Collector = &CheckCollector{}
+var _ manager.LeaderElectionRunnable = &CheckCollector{}
+
+// CheckCollector collects the status of every Check as last synced from healthchecks.io. The metrics are
+// only collected while the collector is started, which is only done in the leading replica, so replicas
+// do not report the same Checks.
+type CheckCollector struct {
+	Client client.Reader
+	Log    logr.Logger
+	Clock  Clock
+
+	leading int32
+}
+
+// NewCheckCollector creates a new CheckCollector listing Checks with c
+func NewCheckCollector(c client.Reader, log logr.Logger) *CheckCollector {
+	return &CheckCollector{
+		Client: c,
+		Log:    log,
+		Clock:  NewClock(),
+	}
+}
+
+// Start has the metrics of Checks collected until stop is closed
+func (c *CheckCollector) Start(stop <-chan struct{}) error {
+	atomic.StoreInt32(&c.leading, 1)
+	<-stop
+	atomic.StoreInt32(&c.leading, 0)
+	return nil
+}
+
+// NeedLeaderElection ensures only the leading replica of the operator reports the metrics of Checks
+func (c *CheckCollector) NeedLeaderElection() bool {
+	return true
+}
+
+// Describe sends the descriptions of the metrics of Checks
+func (c *CheckCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- checkStatusDesc
+	ch <- checkPingsDesc
+	ch <- checkLastPingAgeDesc
+}
+
+// Collect sends the status, pings and time since the last ping of every Check synced with healthchecks.io
+func (c *CheckCollector) Collect(ch chan<- prometheus.Metric) {
+	if atomic.LoadInt32(&c.leading) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	var checks monitoringv1alpha1.CheckList
+	if err := c.Client.List(ctx, &checks); err != nil {
+		c.Log.Error(err, "unable to list Checks for metrics")
+		return
+	}
+
+	now := c.Clock.Now().Time
+	for _, check := range checks.Items {
+		if check.Status.ID == "" {
+			continue
+		}
+
+		tags := append([]string{}, check.Spec.Tags...)
+		sort.Strings(tags)
+		for _, status := range checkStatuses {
+			value := 0.0
+			if check.Status.Status == status {
+				value = 1
+			}
+			ch <- prometheus.MustNewConstMetric(checkStatusDesc, prometheus.GaugeValue, value, check.Namespace, check.Name, strings.Join(tags, ","), status)
+		}
+
+		if check.Status.Pings != nil {
+			ch <- prometheus.MustNewConstMetric(checkPingsDesc, prometheus.CounterValue, float64(*check.Status.Pings), check.Namespace, check.Name)
+		}
+
+		if check.Status.LastPing != nil {
+			ch <- prometheus.MustNewConstMetric(checkLastPingAgeDesc, prometheus.GaugeValue, now.Sub(check.Status.LastPing.Time).Seconds(), check.Namespace, check.Name)
+		}
+	}
+}
+
+// InstrumentClient returns a copy of the http client recording the latency and errors of its requests to the healthchecks.io API
+func InstrumentClient(c *http.Client) *http.Client {
+	instrumented := *c
+	transport := c.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	instrumented.Transport = &instrumentedTransport{next: transport}
+	return &instrumented
+}
+
+// instrumentedTransport records the latency and errors of requests in the API metrics
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request, recording its latency and whether it failed
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.next.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+	}
+	endpoint := apiEndpoint(req.URL.Path)
+
+	apiRequestDuration.WithLabelValues(endpoint, req.Method, code).Observe(time.Since(start).Seconds())
+	if err != nil || res.StatusCode >= 400 {
+		apiRequestErrors.WithLabelValues(endpoint, req.Method, code).Inc()
+	}
+
+	return res, err
+}
+
+// apiEndpoint returns the path of a request relative to the base URL of the API, with the ids of checks replaced by {id}
+func apiEndpoint(path string) string {
+	segments := strings.Split(path, "/")
+
+	start := 0
+	for i, s := range segments {
+		if s == "checks" || s == "channels" {
+			start = i
+			break
+		}
+	}
+
+	endpoint := segments[start:]
+	for i, s := range endpoint {
+		if monitoringv1alpha1.UUIDPattern.MatchString(s) {
+			endpoint[i] = "{id}"
+		}
+	}
+
+	return "/" + strings.TrimPrefix(strings.Join(endpoint, "/"), "/")
+}
+
+// setUnresolvedChannels records the number of channels of the check not matching a channel in healthchecks.io
+func setUnresolvedChannels(check monitoringv1alpha1.Check, count int) {
+	channelsUnresolved.WithLabelValues(check.Namespace, check.Name).Set(float64(count))
+}
+
+// deleteCheckMetrics removes the metrics recorded for the check
+func deleteCheckMetrics(check monitoringv1alpha1.Check) {
+	channelsUnresolved.DeleteLabelValues(check.Namespace, check.Name)
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	hctestutil "github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestCheckCollector_Collect(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	lastPing := metav1.NewTime(now.Add(-90 * time.Second))
+	pings := int32(42)
+
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{}, &monitoringv1alpha1.CheckList{})
+	collector := NewCheckCollector(fake.NewFakeClientWithScheme(s,
+		&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec:       monitoringv1alpha1.CheckSpec{Tags: []string{"prod", "backup"}},
+			Status:     monitoringv1alpha1.CheckStatus{ID: "e71024f4-8537-4dd2-b742-ebe5a1685776", Status: "down", Pings: &pings, LastPing: &lastPing},
+		},
+		&monitoringv1alpha1.Check{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-synced", Namespace: "default"},
+		},
+	), hctestutil.LogrTestLogger{T: t})
+	collector.Clock = Clock{Source: func() *metav1.Time { return &now }}
+
+	// Act & assert, nothing is collected until leading
+	g.Expect(testutil.CollectAndCompare(collector, strings.NewReader(""))).To(Succeed())
+	stop := make(chan struct{})
+	defer close(stop)
+	go collector.Start(stop)
+
+	// Act & assert
+	expected := `
+# HELP healthchecksio_check_last_ping_age_seconds Seconds since the check of a Check last received a ping.
+# TYPE healthchecksio_check_last_ping_age_seconds gauge
+healthchecksio_check_last_ping_age_seconds{name="foo",namespace="default"} 90
+# HELP healthchecksio_check_pings_total Number of pings received by the check of a Check.
+# TYPE healthchecksio_check_pings_total counter
+healthchecksio_check_pings_total{name="foo",namespace="default"} 42
+# HELP healthchecksio_check_status Status of the check of a Check in healthchecks.io, 1 for the current status.
+# TYPE healthchecksio_check_status gauge
+healthchecksio_check_status{name="foo",namespace="default",status="down",tags="backup,prod"} 1
+healthchecksio_check_status{name="foo",namespace="default",status="grace",tags="backup,prod"} 0
+healthchecksio_check_status{name="foo",namespace="default",status="new",tags="backup,prod"} 0
+healthchecksio_check_status{name="foo",namespace="default",status="paused",tags="backup,prod"} 0
+healthchecksio_check_status{name="foo",namespace="default",status="up",tags="backup,prod"} 0
+`
+	g.Eventually(func() error {
+		return testutil.CollectAndCompare(collector, strings.NewReader(expected))
+	}).Should(Succeed())
+}
+
+func TestInstrumentClient(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/pause") {
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := InstrumentClient(&http.Client{})
+	errors := apiRequestErrors.WithLabelValues("/checks/{id}/pause", http.MethodPost, "404")
+	before := testutil.ToFloat64(errors)
+
+	// Act
+	_, err := client.Get(server.URL + "/api/v1/checks/")
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = client.Post(server.URL+"/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776/pause", "application/json", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Assert
+	g.Expect(testutil.ToFloat64(errors) - before).To(Equal(1.0))
+	g.Expect(testutil.ToFloat64(apiRequestErrors.WithLabelValues("/checks/", http.MethodGet, "200"))).To(BeZero())
+}
+
+func TestAPIEndpoint(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(apiEndpoint("/api/v1/checks/")).To(Equal("/checks/"))
+	g.Expect(apiEndpoint("/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776")).To(Equal("/checks/{id}"))
+	g.Expect(apiEndpoint("/checks/e71024f4-8537-4dd2-b742-ebe5a1685776/pings/")).To(Equal("/checks/{id}/pings/"))
+	g.Expect(apiEndpoint("/api/v1/channels/")).To(Equal("/channels/"))
+}
@@ -0,0 +1,197 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// StatusIDField is the field index of Checks by the ID of their check in healthchecks.io
+const StatusIDField = ".status.id"
+
+// NotificationPath is the path notifications from healthchecks.io are received on
+const NotificationPath = "/notify"
+
+// NotificationSecretHeader is the header holding the shared secret of a notification
+const NotificationSecretHeader = "X-Healthchecks-Secret"
+
+// maxNotificationBytes bounds the size of the body of a notification
+const maxNotificationBytes = 64 * 1024
+
+// Timeouts of the notification endpoint, which is reachable before the secret of a request is read,
+// so slow clients do not hold on to connections
+const (
+	notificationReadHeaderTimeout = 5 * time.Second
+	notificationReadTimeout       = 10 * time.Second
+	notificationWriteTimeout      = 10 * time.Second
+	notificationIdleTimeout       = 60 * time.Second
+	maxNotificationHeaderBytes    = 8 * 1024
+)
+
+var _ manager.Runnable = &NotificationReceiver{}
+var _ manager.LeaderElectionRunnable = &NotificationReceiver{}
+
+// NotificationReceiver serves an endpoint configured as a webhook integration in healthchecks.io,
+// enqueueing the Check of the check a notification is sent for so its status is refreshed right away
+type NotificationReceiver struct {
+	Client client.Reader
+	Log    logr.Logger
+	Addr   string
+	Secret string
+	Events chan<- event.GenericEvent
+
+	// Poller is told to forget the polled state of the check a notification is sent for
+	Poller *StatusPoller
+}
+
+// notification is the body of a notification, configured in healthchecks.io as {"code": "$CODE", "status": "$STATUS"}
+type notification struct {
+	Code   string `json:"code"`
+	Status string `json:"status"`
+}
+
+// IndexStatusID indexes Checks by the ID of their check in healthchecks.io
+func IndexStatusID(obj runtime.Object) []string {
+	check, ok := obj.(*monitoringv1alpha1.Check)
+	if !ok || check.Status.ID == "" {
+		return nil
+	}
+	return []string{check.Status.ID}
+}
+
+// Start serves notifications until stop is closed
+func (nr *NotificationReceiver) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(NotificationPath, nr)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: notificationReadHeaderTimeout,
+		ReadTimeout:       notificationReadTimeout,
+		WriteTimeout:      notificationWriteTimeout,
+		IdleTimeout:       notificationIdleTimeout,
+		MaxHeaderBytes:    maxNotificationHeaderBytes,
+	}
+
+	listener, err := net.Listen("tcp", nr.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			nr.Log.Error(err, "unable to shut down the notification receiver")
+		}
+	}()
+
+	nr.Log.V(0).Info("serving notifications", "addr", nr.Addr, "path", NotificationPath)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// NeedLeaderElection ensures notifications are received by the replica running the controllers.
+// Other replicas do not listen on Addr, the Service of config/notification selects the pod labeled by
+// the LeaderLabeler so it routes notifications to the leader only
+func (nr *NotificationReceiver) NeedLeaderElection() bool {
+	return true
+}
+
+// ServeHTTP authenticates a notification and enqueues the Check it is sent for
+func (nr *NotificationReceiver) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	secret := req.Header.Get(NotificationSecretHeader)
+	if nr.Secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(nr.Secret)) != 1 {
+		http.Error(res, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var n notification
+	if err := json.NewDecoder(http.MaxBytesReader(res, req.Body, maxNotificationBytes)).Decode(&n); err != nil || n.Code == "" {
+		http.Error(res, "expected a body in the format {\"code\": \"$CODE\", \"status\": \"$STATUS\"}", http.StatusBadRequest)
+		return
+	}
+
+	log := nr.Log.WithValues("id", n.Code, "status", n.Status)
+
+	checks, err := nr.checksByID(req.Context(), n.Code)
+	if err != nil {
+		log.Error(err, "unable to find the Check of the notification")
+		http.Error(res, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if len(checks) == 0 {
+		log.V(1).Info("no Check found for notification")
+		res.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if nr.Poller != nil {
+		nr.Poller.Invalidate(n.Code)
+	}
+
+	for i := range checks {
+		check := &checks[i]
+		select {
+		case nr.Events <- event.GenericEvent{Meta: check, Object: check}:
+			log.V(0).Info(fmt.Sprintf("received notification, enqueued Check %s/%s", check.Namespace, check.Name))
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	res.WriteHeader(http.StatusAccepted)
+}
+
+// checksByID returns the Checks of the check in healthchecks.io with the id
+func (nr *NotificationReceiver) checksByID(ctx context.Context, id string) ([]monitoringv1alpha1.Check, error) {
+	var list monitoringv1alpha1.CheckList
+	if err := nr.Client.List(ctx, &list, client.MatchingFields{StatusIDField: id}); err != nil {
+		return nil, err
+	}
+
+	checks := make([]monitoringv1alpha1.Check, 0, len(list.Items))
+	for _, c := range list.Items {
+		if c.Status.ID == id {
+			checks = append(checks, c)
+		}
+	}
+
+	return checks, nil
+}
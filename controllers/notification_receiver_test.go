@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestNotificationReceiver_IndexStatusID(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(IndexStatusID(&monitoringv1alpha1.Check{Status: monitoringv1alpha1.CheckStatus{ID: "1"}})).To(Equal([]string{"1"}))
+	g.Expect(IndexStatusID(&monitoringv1alpha1.Check{})).To(BeNil())
+}
+
+func TestNotificationReceiver_EnqueueCheck(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Status:     monitoringv1alpha1.CheckStatus{ID: "e71024f4-8537-4dd2-b742-ebe5a1685776"},
+	}
+	other := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "default"},
+		Status:     monitoringv1alpha1.CheckStatus{ID: "0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"},
+	}
+	nr, events := newNotificationReceiver(t, check, other)
+
+	// Act
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, NotificationPath, strings.NewReader(`{"code": "e71024f4-8537-4dd2-b742-ebe5a1685776", "status": "down"}`))
+	req.Header.Set(NotificationSecretHeader, "secret")
+	nr.ServeHTTP(res, req)
+
+	// Assert
+	g.Expect(res.Code).To(Equal(http.StatusAccepted))
+	g.Expect(events).To(HaveLen(1))
+	e := <-events
+	g.Expect(e.Meta.GetName()).To(Equal("foo"))
+}
+
+func TestNotificationReceiver_Rejected(t *testing.T) {
+	g := NewGomegaWithT(t)
+	nr, events := newNotificationReceiver(t)
+	body := `{"code": "e71024f4-8537-4dd2-b742-ebe5a1685776", "status": "down"}`
+
+	serve := func(method, target, secret, body string) int {
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if secret != "" {
+			req.Header.Set(NotificationSecretHeader, secret)
+		}
+		nr.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	g.Expect(serve(http.MethodPost, NotificationPath, "", body)).To(Equal(http.StatusUnauthorized))
+	g.Expect(serve(http.MethodPost, NotificationPath, "wrong", body)).To(Equal(http.StatusUnauthorized))
+	g.Expect(serve(http.MethodGet, NotificationPath, "secret", "")).To(Equal(http.StatusMethodNotAllowed))
+	g.Expect(serve(http.MethodPost, NotificationPath, "secret", "{}")).To(Equal(http.StatusBadRequest))
+	g.Expect(serve(http.MethodPost, NotificationPath+"?secret=secret", "", body)).To(Equal(http.StatusUnauthorized))
+	g.Expect(events).To(BeEmpty())
+}
+
+func newNotificationReceiver(t *testing.T, objs ...runtime.Object) (*NotificationReceiver, chan event.GenericEvent) {
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{}, &monitoringv1alpha1.CheckList{})
+	events := make(chan event.GenericEvent, 10)
+
+	return &NotificationReceiver{
+		Client: fake.NewFakeClientWithScheme(s, objs...),
+		Log:    testutil.LogrTestLogger{T: t},
+		Secret: "secret",
+		Events: events,
+	}, events
+}
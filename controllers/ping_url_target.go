@@ -0,0 +1,215 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+const (
+	defaultPingURLTargetKey = "HC_PING_URL"
+
+	// LabelPingURLTarget marks the Secrets and ConfigMaps created by the operator to hold ping urls
+	LabelPingURLTarget = "healthchecks.io/ping-url-target"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// reconcilePingURLTarget writes the ping URLs of the check to the Secret or ConfigMap referenced by the check,
+// deleting the targets created for the check that it no longer references
+func (r *CheckReconciler) reconcilePingURLTarget(ctx context.Context, check *monitoringv1alpha1.Check) (controllerutil.OperationResult, error) {
+	if err := r.deleteStalePingURLTargets(ctx, check); err != nil {
+		return controllerutil.OperationResultNone, err
+	}
+
+	target := check.Spec.PingURLTarget
+	if target == nil || check.Status.PingURL == "" {
+		return controllerutil.OperationResultNone, nil
+	}
+
+	data := PingURLs(check.Status.PingURL, target.Key)
+	meta := metav1.ObjectMeta{
+		Name:      target.Name,
+		Namespace: check.Namespace,
+	}
+
+	var obj runtime.Object
+	var mutate func() error
+	switch target.Kind {
+	case "Secret":
+		secret := &corev1.Secret{ObjectMeta: meta}
+		obj = secret
+		mutate = func() error {
+			// the data is replaced, so keys of a previous spec.pingURLTarget.key are removed
+			secret.Data = make(map[string][]byte, len(data))
+			for k, v := range data {
+				secret.Data[k] = []byte(v)
+			}
+			return r.claimPingURLTarget(check, secret)
+		}
+	case "ConfigMap":
+		configMap := &corev1.ConfigMap{ObjectMeta: meta}
+		obj = configMap
+		mutate = func() error {
+			configMap.Data = data
+			return r.claimPingURLTarget(check, configMap)
+		}
+	default:
+		return controllerutil.OperationResultNone, fmt.Errorf("unsupported ping url target kind %s", target.Kind)
+	}
+
+	// the target is read from the API server, only labeled targets are cached
+	c := client.Client(r.Client)
+	if r.APIReader != nil {
+		c = &client.DelegatingClient{Reader: r.APIReader, Writer: r.Client, StatusClient: r.Client}
+	}
+
+	// objects not created for the check are never written, so they are not taken over
+	// and garbage collected along with the check
+	if err := c.Get(ctx, types.NamespacedName{Name: meta.Name, Namespace: meta.Namespace}, obj); err == nil {
+		if existing, ok := obj.(metav1.Object); ok && !metav1.IsControlledBy(existing, check) {
+			return controllerutil.OperationResultNone, fmt.Errorf("%s %s already exists and is not managed by the Check", target.Kind, target.Name)
+		}
+	} else if !apierrs.IsNotFound(err) {
+		return controllerutil.OperationResultNone, err
+	}
+
+	return controllerutil.CreateOrUpdate(ctx, c, obj, mutate)
+}
+
+// deleteStalePingURLTargets deletes the Secrets and ConfigMaps created for the check other than its
+// current ping url target, left behind when the target was renamed, changed kind or removed
+func (r *CheckReconciler) deleteStalePingURLTargets(ctx context.Context, check *monitoringv1alpha1.Check) error {
+	targets, err := r.listPingURLTargets(ctx, check.Namespace)
+	if err != nil {
+		return err
+	}
+
+	target := check.Spec.PingURLTarget
+	for _, obj := range targets {
+		kind := "Secret"
+		if _, ok := obj.(*corev1.ConfigMap); ok {
+			kind = "ConfigMap"
+		}
+
+		meta := obj.(metav1.Object)
+		if !metav1.IsControlledBy(meta, check) || (target != nil && target.Kind == kind && target.Name == meta.GetName()) {
+			continue
+		}
+
+		if err := r.Delete(ctx, obj); err != nil && !apierrs.IsNotFound(err) {
+			return err
+		}
+		r.Log.V(1).Info("deleted stale ping url target", "check", fmt.Sprintf("%s/%s", check.Namespace, check.Name), "kind", kind, "name", meta.GetName())
+	}
+
+	return nil
+}
+
+// listPingURLTargets lists the Secrets and ConfigMaps labeled as ping url targets in the namespace, from the
+// informers watching them when the controller is running
+func (r *CheckReconciler) listPingURLTargets(ctx context.Context, namespace string) ([]runtime.Object, error) {
+	selector := labels.SelectorFromSet(labels.Set{LabelPingURLTarget: "true"})
+	targets := make([]runtime.Object, 0)
+
+	if r.pingURLTargets != nil {
+		secrets, err := r.pingURLTargets.Core().V1().Secrets().Lister().Secrets(namespace).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range secrets {
+			targets = append(targets, secret.DeepCopy())
+		}
+
+		configMaps, err := r.pingURLTargets.Core().V1().ConfigMaps().Lister().ConfigMaps(namespace).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, configMap := range configMaps {
+			targets = append(targets, configMap.DeepCopy())
+		}
+
+		return targets, nil
+	}
+
+	opts := &client.ListOptions{Namespace: namespace, LabelSelector: selector}
+	var secrets corev1.SecretList
+	if err := r.List(ctx, &secrets, opts); err != nil {
+		return nil, err
+	}
+	for i := range secrets.Items {
+		targets = append(targets, &secrets.Items[i])
+	}
+
+	var configMaps corev1.ConfigMapList
+	if err := r.List(ctx, &configMaps, opts); err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		targets = append(targets, &configMaps.Items[i])
+	}
+
+	return targets, nil
+}
+
+// claimPingURLTarget labels a Secret or ConfigMap holding ping urls and sets the check as its controller
+func (r *CheckReconciler) claimPingURLTarget(check *monitoringv1alpha1.Check, obj metav1.Object) error {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[LabelPingURLTarget] = "true"
+	obj.SetLabels(labels)
+
+	return controllerutil.SetControllerReference(check, obj, r.Scheme)
+}
+
+// pingURLTargetInformers returns a factory of informers listing and watching only the
+// Secrets and ConfigMaps labeled as ping url targets
+func pingURLTargetInformers(clientset kubernetes.Interface) informers.SharedInformerFactory {
+	return informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+		opts.LabelSelector = LabelPingURLTarget + "=true"
+	}))
+}
+
+// PingURLs returns the ping URL and the URLs derived from it, keyed by the given key
+func PingURLs(pingURL, key string) map[string]string {
+	if key == "" {
+		key = defaultPingURLTargetKey
+	}
+
+	return map[string]string{
+		key:            pingURL,
+		key + "_START": pingURL + "/start",
+		key + "_FAIL":  pingURL + "/fail",
+		key + "_LOG":   pingURL + "/log",
+	}
+}
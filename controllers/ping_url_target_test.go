@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+func TestPingURLTarget_PingURLs(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(PingURLs("https://hc-ping.com/id", "")).To(Equal(map[string]string{
+		"HC_PING_URL":       "https://hc-ping.com/id",
+		"HC_PING_URL_START": "https://hc-ping.com/id/start",
+		"HC_PING_URL_FAIL":  "https://hc-ping.com/id/fail",
+		"HC_PING_URL_LOG":   "https://hc-ping.com/id/log",
+	}))
+
+	g.Expect(PingURLs("https://hc-ping.com/id", "url")).To(HaveKeyWithValue("url_FAIL", "https://hc-ping.com/id/fail"))
+}
+
+func TestPingURLTarget_NotSet(t *testing.T) {
+	// Arrange
+	check := newPingURLTargetCheck(nil)
+	ctx := NewCheckReconcilerTest(t, WithK8sObjects(check))
+	defer func() { ctx.Close() }()
+
+	// Act
+	op, err := ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(op).To(Equal(controllerutil.OperationResultNone))
+}
+
+func TestPingURLTarget_Secret(t *testing.T) {
+	// Arrange
+	check := newPingURLTargetCheck(&monitoringv1alpha1.PingURLTarget{Kind: "Secret", Name: "ping-urls"})
+	ctx := NewCheckReconcilerTest(t, WithK8sObjects(check))
+	defer func() { ctx.Close() }()
+
+	// Act
+	op, err := ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(op).To(Equal(controllerutil.OperationResultCreated))
+
+	secret := &corev1.Secret{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "ping-urls", Namespace: check.Namespace}, secret)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(secret.Data).To(HaveKeyWithValue("HC_PING_URL", []byte(check.Status.PingURL)))
+	ctx.t.Expect(secret.Data).To(HaveKeyWithValue("HC_PING_URL_START", []byte(check.Status.PingURL+"/start")))
+	ctx.t.Expect(secret.OwnerReferences).To(HaveLen(1))
+	ctx.t.Expect(secret.OwnerReferences[0].Name).To(Equal(check.Name))
+
+	// Act
+	op, err = ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(op).To(Equal(controllerutil.OperationResultNone), "nothing changed since last reconcile")
+}
+
+func TestPingURLTarget_ConfigMap(t *testing.T) {
+	// Arrange
+	check := newPingURLTargetCheck(&monitoringv1alpha1.PingURLTarget{Kind: "ConfigMap", Name: "ping-urls", Key: "PING"})
+	ctx := NewCheckReconcilerTest(t, WithK8sObjects(check))
+	defer func() { ctx.Close() }()
+
+	// Act
+	op, err := ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(op).To(Equal(controllerutil.OperationResultCreated))
+
+	configMap := &corev1.ConfigMap{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "ping-urls", Namespace: check.Namespace}, configMap)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(configMap.Labels).To(HaveKeyWithValue(LabelPingURLTarget, "true"))
+	ctx.t.Expect(configMap.Data).To(HaveKeyWithValue("PING", check.Status.PingURL))
+	ctx.t.Expect(configMap.Data).To(HaveKeyWithValue("PING_LOG", check.Status.PingURL+"/log"))
+
+	// Act, the ping url changes
+	check.Status.PingURL = "https://hc-ping.com/0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"
+	op, err = ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(op).To(Equal(controllerutil.OperationResultUpdated))
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "ping-urls", Namespace: check.Namespace}, configMap)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(configMap.Data).To(HaveKeyWithValue("PING", check.Status.PingURL))
+
+	// Act, the key changes
+	check.Spec.PingURLTarget.Key = "URL"
+	op, err = ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert, the keys of the previous key are removed
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(op).To(Equal(controllerutil.OperationResultUpdated))
+	configMap = &corev1.ConfigMap{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "ping-urls", Namespace: check.Namespace}, configMap)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(configMap.Data).To(Equal(PingURLs(check.Status.PingURL, "URL")))
+}
+
+func TestPingURLTarget_ExistingObject(t *testing.T) {
+	// Arrange
+	check := newPingURLTargetCheck(&monitoringv1alpha1.PingURLTarget{Kind: "ConfigMap", Name: "ping-urls", Key: "PING"})
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ping-urls",
+			Namespace: check.Namespace,
+		},
+		Data: map[string]string{
+			"OTHER": "value",
+		},
+	}
+	ctx := NewCheckReconcilerTest(t, WithK8sObjects(check, existing))
+	defer func() { ctx.Close() }()
+
+	// Act
+	_, err := ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert, objects not created for the check are left untouched
+	ctx.t.Expect(err).To(MatchError("ConfigMap ping-urls already exists and is not managed by the Check"))
+
+	configMap := &corev1.ConfigMap{}
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "ping-urls", Namespace: check.Namespace}, configMap)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	ctx.t.Expect(configMap.Data).To(Equal(map[string]string{"OTHER": "value"}))
+	ctx.t.Expect(configMap.OwnerReferences).To(BeEmpty())
+}
+
+func TestPingURLTarget_StaleTargets(t *testing.T) {
+	// Arrange
+	check := newPingURLTargetCheck(&monitoringv1alpha1.PingURLTarget{Kind: "Secret", Name: "ping-urls"})
+	other := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-ping-urls",
+			Namespace: check.Namespace,
+			Labels:    map[string]string{LabelPingURLTarget: "true"},
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: monitoringv1alpha1.GroupVersion.String(),
+				Kind:       "Check",
+				Name:       "other",
+				UID:        "c4d5e6f7-0a1b-4c2d-8e3f-4a5b6c7d8e9f",
+				Controller: &[]bool{true}[0],
+			}},
+		},
+	}
+	ctx := NewCheckReconcilerTest(t, WithK8sObjects(check, other))
+	defer func() { ctx.Close() }()
+	_, err := ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Act, the target is renamed
+	check.Spec.PingURLTarget.Name = "renamed-ping-urls"
+	_, err = ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "ping-urls", Namespace: check.Namespace}, &corev1.Secret{})
+	ctx.t.Expect(apierrs.IsNotFound(err)).To(BeTrue(), "the secret of the old name is deleted")
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "renamed-ping-urls", Namespace: check.Namespace}, &corev1.Secret{})
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Act, the target becomes a ConfigMap of the same name
+	check.Spec.PingURLTarget.Kind = "ConfigMap"
+	_, err = ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "renamed-ping-urls", Namespace: check.Namespace}, &corev1.Secret{})
+	ctx.t.Expect(apierrs.IsNotFound(err)).To(BeTrue(), "the secret is deleted")
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "renamed-ping-urls", Namespace: check.Namespace}, &corev1.ConfigMap{})
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+
+	// Act, the target is removed
+	check.Spec.PingURLTarget = nil
+	_, err = ctx.Reconciler.reconcilePingURLTarget(context.TODO(), check)
+
+	// Assert
+	ctx.t.Expect(err).ToNot(HaveOccurred())
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "renamed-ping-urls", Namespace: check.Namespace}, &corev1.ConfigMap{})
+	ctx.t.Expect(apierrs.IsNotFound(err)).To(BeTrue(), "the config map is deleted")
+	err = ctx.Reconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "other-ping-urls", Namespace: check.Namespace}, &corev1.Secret{})
+	ctx.t.Expect(err).ToNot(HaveOccurred(), "targets of other checks are left as they are")
+}
+
+func TestPingURLTarget_Informers(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	clientset := k8sfake.NewSimpleClientset(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "target", Namespace: "bar", Labels: map[string]string{LabelPingURLTarget: "true"}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "api-key", Namespace: "bar"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "bar"}},
+	)
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// Act
+	factory := pingURLTargetInformers(clientset)
+	secrets := factory.Core().V1().Secrets().Informer()
+	configMaps := factory.Core().V1().ConfigMaps().Informer()
+	factory.Start(stop)
+	factory.WaitForCacheSync(stop)
+
+	// Assert
+	g.Expect(secrets.GetStore().ListKeys()).To(ConsistOf("bar/target"))
+	g.Expect(configMaps.GetStore().ListKeys()).To(BeEmpty())
+}
+
+func newPingURLTargetCheck(target *monitoringv1alpha1.PingURLTarget) *monitoringv1alpha1.Check {
+	return &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+			UID:       "b0f3f7a8-5b4c-4e4d-9b39-7c1e4a0a7f1e",
+		},
+		Spec: monitoringv1alpha1.CheckSpec{
+			PingURLTarget: target,
+		},
+		Status: monitoringv1alpha1.CheckStatus{
+			PingURL: "https://hc-ping.com/e71024f4-8537-4dd2-b742-ebe5a1685776",
+		},
+	}
+}
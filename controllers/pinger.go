@@ -0,0 +1,87 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Pinger sends pings to the ping URLs of checks
+type Pinger struct {
+	HTTPClient *http.Client
+
+	clients map[string]*http.Client
+	mu      sync.Mutex
+}
+
+// NewPinger creates a new Pinger using httpClient to send pings
+func NewPinger(httpClient *http.Client) *Pinger {
+	return &Pinger{
+		HTTPClient: httpClient,
+		clients:    make(map[string]*http.Client),
+	}
+}
+
+// Ping sends a ping to url, with body as the payload of the ping. The CA bundle and
+// insecureSkipVerify of opts are used to reach the instance of a project.
+func (p *Pinger) Ping(opts ClientOptions, url string, body []byte) error {
+	httpClient, err := p.client(opts)
+	if err != nil {
+		return err
+	}
+
+	res, err := httpClient.Post(url, "text/plain", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("ping %s failed with status %s", url, res.Status)
+	}
+
+	return nil
+}
+
+// client returns the http client for the TLS settings of opts, creating it when it does not exist
+func (p *Pinger) client(opts ClientOptions) (*http.Client, error) {
+	if len(opts.CABundle) == 0 && !opts.InsecureSkipVerify {
+		return p.HTTPClient, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// ping URLs are not below the base URL of the API
+	opts.BaseURL = ""
+	key := opts.key()
+	if httpClient, ok := p.clients[key]; ok {
+		return httpClient, nil
+	}
+
+	httpClient, err := NewHTTPClient(opts.CABundle, opts.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	// keep the timeout of the default client, pings must never hang
+	httpClient.Timeout = p.HTTPClient.Timeout
+
+	p.clients[key] = httpClient
+	return httpClient, nil
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+func TestPinger_Ping_ProjectTLS(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	pinger := NewPinger(&http.Client{Timeout: 10 * time.Second})
+
+	// Act & Assert
+	g.Expect(pinger.Ping(ClientOptions{}, server.URL+"/ping", nil)).To(HaveOccurred())
+	g.Expect(pinger.Ping(ClientOptions{CABundle: caBundle}, server.URL+"/ping", nil)).To(Succeed())
+	g.Expect(pinger.Ping(ClientOptions{InsecureSkipVerify: true}, server.URL+"/ping", nil)).To(Succeed())
+
+	httpClient, _ := pinger.client(ClientOptions{CABundle: caBundle})
+	g.Expect(httpClient.Timeout).To(Equal(10 * time.Second))
+	g.Expect(pinger.client(ClientOptions{BaseURL: "https://hc.example.com/api/v1", CABundle: caBundle})).To(BeIdenticalTo(httpClient))
+}
+
+func TestPinger_PingOptions(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.HealthchecksProject{}, &monitoringv1alpha1.HealthchecksProjectList{})
+	project := &monitoringv1alpha1.HealthchecksProject{
+		ObjectMeta: metav1.ObjectMeta{Name: "self-hosted", Namespace: "default"},
+		Spec: monitoringv1alpha1.HealthchecksProjectSpec{
+			BaseURL:            "https://hc.example.com/api/v1",
+			CABundle:           []byte("ca"),
+			InsecureSkipVerify: true,
+		},
+	}
+	c := fake.NewFakeClientWithScheme(s, project)
+
+	// Act
+	opts, err := pingOptions(context.TODO(), c, &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: monitoringv1alpha1.CheckSpec{
+			ProjectRef: &monitoringv1alpha1.ProjectReference{Name: "self-hosted"},
+		},
+	})
+	defaults, _ := pingOptions(context.TODO(), c, &monitoringv1alpha1.Check{})
+
+	// Assert
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(opts).To(Equal(ClientOptions{BaseURL: "https://hc.example.com/api/v1", CABundle: []byte("ca"), InsecureSkipVerify: true}))
+	g.Expect(defaults).To(Equal(ClientOptions{}))
+}
@@ -0,0 +1,288 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// Reasons used for events recorded on a Check
+const (
+	EventReasonProbeFailed    = "ProbeFailed"
+	EventReasonProbeRecovered = "ProbeRecovered"
+)
+
+// resourceProbeInterval is how often the resource probe of a check is evaluated
+const resourceProbeInterval = time.Minute
+
+// ProbeReconciler runs the probes of checks every timeout period, and the resource probes of checks
+// every minute, and pings the checks when their probe succeeds
+type ProbeReconciler struct {
+	client.Client
+	Log      logr.Logger
+	Recorder record.EventRecorder
+	Pinger   *Pinger
+	Prober   *Prober
+	Clock    Clock
+
+	// MaxConcurrentReconciles is the number of probes run at the same time, so slow or unreachable
+	// targets do not hold up the probes of other checks
+	MaxConcurrentReconciles int
+}
+
+// +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=checks,verbs=get;list;watch
+// +kubebuilder:rbac:groups=monitoring.healthchecks.io,resources=checks/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets;replicasets,verbs=get
+// +kubebuilder:rbac:groups=batch,resources=jobs;cronjobs,verbs=get
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates;issuers,verbs=get
+
+// Reconcile runs the probe of the check when it is due. Only the probe fields of the status are
+// written, leaving the fields written by the Check controller as they are.
+func (r *ProbeReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	log := r.Log.WithValues("check", req.NamespacedName)
+
+	var check monitoringv1alpha1.Check
+	if err := r.Get(ctx, req.NamespacedName, &check); err != nil {
+		log.V(1).Info("unable to fetch Check from k8s")
+		return ctrl.Result{}, ignoreNotFound(err)
+	}
+
+	if !check.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	var period time.Duration
+	switch {
+	case check.Spec.Probe != nil && check.Spec.Timeout != nil:
+		period = time.Duration(*check.Spec.Timeout) * time.Second
+	case check.Spec.Probe != nil:
+		return ctrl.Result{}, r.reportNoTimeout(ctx, check)
+	case check.Spec.ResourceProbe != nil:
+		period = resourceProbeInterval
+	default:
+		return ctrl.Result{}, r.removeProbeStatus(ctx, check)
+	}
+
+	if check.Status.PingURL == "" {
+		log.V(1).Info("the Check has no ping url yet, skipping probe")
+		return ctrl.Result{}, nil
+	}
+
+	now := r.Clock.Now()
+	if check.Status.LastProbe != nil {
+		if wait := check.Status.LastProbe.Add(period).Sub(now.Time); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	opts, err := pingOptions(ctx, r.Client, &check)
+	if err != nil {
+		log.Error(err, "unable to resolve the project of the Check")
+		return ctrl.Result{}, err
+	}
+
+	previous := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded)
+	failing := previous != nil && previous.Status == metav1.ConditionFalse
+
+	condition := monitoringv1alpha1.Condition{
+		Type:   monitoringv1alpha1.ConditionProbeSucceeded,
+		Status: metav1.ConditionTrue,
+		Reason: "Succeeded",
+	}
+	if err := r.probe(ctx, check); err != nil {
+		log.V(0).Info("probe failed", "error", err.Error())
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Failed"
+		if IsProbeNetworkNotAllowed(err) {
+			condition.Reason = "ProbeNetworkNotAllowed"
+		}
+		condition.Message = err.Error()
+		if check.Spec.Probe != nil {
+			condition.Message = ProbeFailureReason(err)
+		}
+
+		// a resource probe reports that its rule stopped holding right away, instead of waiting for the check to go down
+		if check.Spec.ResourceProbe != nil && !failing {
+			if err := r.Pinger.Ping(opts, jobPingURL(check.Status.PingURL, jobPingFail), []byte(condition.Message)); err != nil {
+				log.Error(err, "unable to send fail ping")
+				r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonPingFailed, "Failed to send fail ping for failed probe: %s", err)
+				return ctrl.Result{}, err
+			}
+		}
+	} else if err := r.Pinger.Ping(opts, check.Status.PingURL, nil); err != nil {
+		log.Error(err, "unable to send ping")
+		r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonPingFailed, "Failed to send ping for succeeded probe: %s", err)
+		return ctrl.Result{}, err
+	}
+
+	if condition.Status == metav1.ConditionFalse && !failing {
+		r.Recorder.Eventf(&check, corev1.EventTypeWarning, EventReasonProbeFailed, "Probe failed: %s", condition.Message)
+	} else if condition.Status == metav1.ConditionTrue && failing {
+		r.Recorder.Event(&check, corev1.EventTypeNormal, EventReasonProbeRecovered, "Probe succeeded again")
+	}
+
+	condition.LastTransitionTime = *now
+	err = r.updateProbeStatus(ctx, check, func(check *monitoringv1alpha1.Check) {
+		condition.ObservedGeneration = check.ObjectMeta.Generation
+		monitoringv1alpha1.SetCondition(&check.Status.Conditions, condition)
+		check.Status.LastProbe = now
+	})
+	if err != nil {
+		log.Error(err, "unable to update Check status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: period}, nil
+}
+
+// reportNoTimeout sets the ProbeSucceeded condition of a check with a probe but no timeout, which is never run
+func (r *ProbeReconciler) reportNoTimeout(ctx context.Context, check monitoringv1alpha1.Check) error {
+	if c := monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded); c != nil && c.Reason == "NoTimeout" && c.ObservedGeneration == check.Generation {
+		return nil
+	}
+
+	r.Recorder.Event(&check, corev1.EventTypeWarning, EventReasonProbeFailed, "Probe not run, spec.timeout must be set to run spec.probe")
+	now := r.Clock.Now()
+	return r.updateProbeStatus(ctx, check, func(check *monitoringv1alpha1.Check) {
+		monitoringv1alpha1.SetCondition(&check.Status.Conditions, monitoringv1alpha1.Condition{
+			Type:               monitoringv1alpha1.ConditionProbeSucceeded,
+			Status:             metav1.ConditionFalse,
+			Reason:             "NoTimeout",
+			Message:            "spec.timeout must be set to run spec.probe",
+			ObservedGeneration: check.Generation,
+			LastTransitionTime: *now,
+		})
+	})
+}
+
+// removeProbeStatus removes the ProbeSucceeded condition of a check which no longer has a probe
+func (r *ProbeReconciler) removeProbeStatus(ctx context.Context, check monitoringv1alpha1.Check) error {
+	if monitoringv1alpha1.FindCondition(check.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded) == nil {
+		return nil
+	}
+
+	return r.updateProbeStatus(ctx, check, func(check *monitoringv1alpha1.Check) {
+		monitoringv1alpha1.RemoveCondition(&check.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded)
+	})
+}
+
+// updateProbeStatus applies mutate to the status of the check as read and updates it. The update is
+// rejected when the Check controller wrote the status in between, and retried on the Check read again,
+// so the conditions it wrote are never overwritten.
+func (r *ProbeReconciler) updateProbeStatus(ctx context.Context, check monitoringv1alpha1.Check, mutate func(check *monitoringv1alpha1.Check)) error {
+	key := types.NamespacedName{Name: check.Name, Namespace: check.Namespace}
+	stale := false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if stale {
+			check = monitoringv1alpha1.Check{}
+			if err := r.Get(ctx, key, &check); err != nil {
+				return err
+			}
+		}
+		stale = true
+
+		mutate(&check)
+		return r.Status().Update(ctx, &check)
+	})
+}
+
+// probe runs the probe, or evaluates the resource probe, of the check
+func (r *ProbeReconciler) probe(ctx context.Context, check monitoringv1alpha1.Check) error {
+	if check.Spec.Probe != nil {
+		return r.Prober.Probe(*check.Spec.Probe)
+	}
+
+	rp := check.Spec.ResourceProbe
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(rp.APIVersion)
+	obj.SetKind(rp.Kind)
+	if err := r.Get(ctx, types.NamespacedName{Name: rp.Name, Namespace: check.Namespace}, obj); err != nil {
+		return err
+	}
+
+	return evaluateResourceProbe(*rp, obj)
+}
+
+// evaluateResourceProbe returns an error describing why the rule of the resource probe does not hold for obj
+func evaluateResourceProbe(probe monitoringv1alpha1.ResourceProbe, obj *unstructured.Unstructured) error {
+	if probe.Condition != nil {
+		status := probe.Condition.Status
+		if status == "" {
+			status = string(metav1.ConditionTrue)
+		}
+
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["type"] != probe.Condition.Type {
+				continue
+			}
+			if condition["status"] != status {
+				return fmt.Errorf("%s %s has condition %s=%v, expected %s", probe.Kind, probe.Name, probe.Condition.Type, condition["status"], status)
+			}
+			return nil
+		}
+
+		return fmt.Errorf("%s %s has no condition %s", probe.Kind, probe.Name, probe.Condition.Type)
+	}
+
+	if probe.ReplicasReady {
+		replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if err != nil {
+			return err
+		}
+		if !found {
+			replicas = 1
+		}
+
+		ready, _, err := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		if err != nil {
+			return err
+		}
+
+		if ready != replicas {
+			return fmt.Errorf("%s %s has %d of %d replicas ready", probe.Kind, probe.Name, ready, replicas)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("resource probe has no condition or replicasReady set")
+}
+
+// SetupWithManager hooks up the controller/reconciler
+func (r *ProbeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("probe").
+		For(&monitoringv1alpha1.Check{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		Complete(r)
+}
@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestProbeController_PingOnSuccess(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pings := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/down" {
+			res.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if req.Method == http.MethodPost {
+			pings = append(pings, req.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	up := newProbeCheck("up", server.URL+"/healthz", server.URL+"/c1")
+	down := newProbeCheck("down", server.URL+"/down", server.URL+"/c2")
+	r := newProbeReconciler(t, up, down)
+
+	// Act
+	result, err := r.Reconcile(NewReconcileRequest("up", "default"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(5 * time.Minute))
+	_, err = r.Reconcile(NewReconcileRequest("down", "default"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Assert, only the check with a succeeding probe is pinged
+	g.Expect(pings).To(Equal([]string{"/c1"}))
+
+	actual := &monitoringv1alpha1.Check{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: "up", Namespace: "default"}, actual)).To(Succeed())
+	g.Expect(actual.Status.LastProbe.Time).To(BeTemporally("==", r.Clock.Now().Time))
+	g.Expect(monitoringv1alpha1.IsConditionTrue(actual.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded)).To(BeTrue())
+
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: "down", Namespace: "default"}, actual)).To(Succeed())
+	g.Expect(monitoringv1alpha1.FindCondition(actual.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded).Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(<-r.Recorder.(*record.FakeRecorder).Events).To(HavePrefix("Warning ProbeFailed Probe failed: GET"))
+}
+
+func TestProbeController_KeepsCheckControllerStatus(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	check := newProbeCheck("up", server.URL+"/healthz", server.URL+"/c1")
+	check.ResourceVersion = "1"
+	check.Status.Conditions = []monitoringv1alpha1.Condition{
+		{Type: monitoringv1alpha1.ConditionSynced, Status: metav1.ConditionTrue, Reason: "Synced"},
+		{Type: monitoringv1alpha1.ConditionReady, Status: metav1.ConditionTrue, Reason: "Up"},
+	}
+	r := newProbeReconciler(t, check)
+	c := &concurrentStatusClient{Client: r.Client, write: func(check *monitoringv1alpha1.Check) {
+		check.Status.Status = "down"
+		monitoringv1alpha1.SetCondition(&check.Status.Conditions, monitoringv1alpha1.Condition{Type: monitoringv1alpha1.ConditionSynced, Status: metav1.ConditionFalse, Reason: "SyncFailed"})
+		monitoringv1alpha1.SetCondition(&check.Status.Conditions, monitoringv1alpha1.Condition{Type: monitoringv1alpha1.ConditionReady, Status: metav1.ConditionFalse, Reason: "Down"})
+	}}
+	r.Client = c
+
+	// Act, the Check controller writes the status after the probe read the Check
+	_, err := r.Reconcile(NewReconcileRequest("up", "default"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Assert, the stale status is rejected and only the probe fields are written on the Check read again
+	actual := &monitoringv1alpha1.Check{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: "up", Namespace: "default"}, actual)).To(Succeed())
+	g.Expect(c.conflicts).To(Equal(1))
+	g.Expect(actual.Status.Status).To(Equal("down"))
+	g.Expect(monitoringv1alpha1.FindCondition(actual.Status.Conditions, monitoringv1alpha1.ConditionSynced).Reason).To(Equal("SyncFailed"))
+	g.Expect(monitoringv1alpha1.FindCondition(actual.Status.Conditions, monitoringv1alpha1.ConditionReady).Reason).To(Equal("Down"))
+	g.Expect(actual.Status.LastProbe).ToNot(BeNil())
+	g.Expect(monitoringv1alpha1.IsConditionTrue(actual.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded)).To(BeTrue())
+}
+
+func TestProbeController_NetworkNotAllowed(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+	check := newProbeCheck("loopback", server.URL+"/healthz", server.URL+"/c1")
+	r := newProbeReconciler(t, check)
+	r.Prober = NewProber()
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest("loopback", "default"))
+
+	// Assert, the refused probe is told apart from a failing target
+	g.Expect(err).ToNot(HaveOccurred())
+	actual := &monitoringv1alpha1.Check{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: "loopback", Namespace: "default"}, actual)).To(Succeed())
+	condition := monitoringv1alpha1.FindCondition(actual.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded)
+	g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal("ProbeNetworkNotAllowed"))
+}
+
+func TestProbeController_NotDue(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	check := newProbeCheck("up", "http://127.0.0.1:0/healthz", "http://127.0.0.1:0/c1")
+	r := newProbeReconciler(t, check)
+	lastProbe := metav1.NewTime(r.Clock.Now().Add(-2 * time.Minute))
+	check.Status.LastProbe = &lastProbe
+	g.Expect(r.Update(context.TODO(), check)).To(Succeed())
+
+	// Act, probing would fail as nothing listens on the probe url
+	result, err := r.Reconcile(NewReconcileRequest("up", "default"))
+
+	// Assert
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(3 * time.Minute))
+}
+
+func TestProbeController_ResourceProbe(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	pings := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		pings = append(pings, req.URL.Path)
+	}))
+	defer server.Close()
+
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: 2},
+	}
+	check := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: monitoringv1alpha1.CheckSpec{
+			ResourceProbe: &monitoringv1alpha1.ResourceProbe{APIVersion: "apps/v1", Kind: "Deployment", Name: "api", ReplicasReady: true},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: server.URL + "/c1"},
+	}
+	r := newProbeReconciler(t, deployment, check)
+
+	// Act, the rule holds
+	result, err := r.Reconcile(NewReconcileRequest("api", "default"))
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+	// Act, the rule stops holding, the fail ping is only sent once
+	deployment.Status.ReadyReplicas = 1
+	g.Expect(r.Update(context.TODO(), deployment)).To(Succeed())
+	for i := 1; i <= 2; i++ {
+		later := metav1.NewTime(r.Clock.Now().Add(time.Duration(i) * time.Hour))
+		r.Clock = Clock{Source: func() *metav1.Time { return &later }}
+		_, err = r.Reconcile(NewReconcileRequest("api", "default"))
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	// Assert
+	g.Expect(pings).To(Equal([]string{"/c1", "/c1/fail"}))
+	g.Expect(<-r.Recorder.(*record.FakeRecorder).Events).To(Equal("Warning ProbeFailed Probe failed: Deployment api has 1 of 2 replicas ready"))
+}
+
+func TestProbeController_EvaluateResourceProbe(t *testing.T) {
+	g := NewGomegaWithT(t)
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": int64(3)},
+		"status": map[string]interface{}{
+			"readyReplicas": int64(3),
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+				map[string]interface{}{"type": "Progressing", "status": "False"},
+			},
+		},
+	}}
+	probe := func(condition *monitoringv1alpha1.ResourceCondition, replicasReady bool) monitoringv1alpha1.ResourceProbe {
+		return monitoringv1alpha1.ResourceProbe{Kind: "Deployment", Name: "api", Condition: condition, ReplicasReady: replicasReady}
+	}
+
+	g.Expect(evaluateResourceProbe(probe(&monitoringv1alpha1.ResourceCondition{Type: "Available"}, false), obj)).To(Succeed())
+	g.Expect(evaluateResourceProbe(probe(&monitoringv1alpha1.ResourceCondition{Type: "Progressing", Status: "False"}, false), obj)).To(Succeed())
+	g.Expect(evaluateResourceProbe(probe(&monitoringv1alpha1.ResourceCondition{Type: "Progressing"}, false), obj)).ToNot(Succeed())
+	g.Expect(evaluateResourceProbe(probe(&monitoringv1alpha1.ResourceCondition{Type: "Ready"}, false), obj)).ToNot(Succeed())
+	g.Expect(evaluateResourceProbe(probe(nil, true), obj)).To(Succeed())
+	g.Expect(evaluateResourceProbe(probe(nil, true), &unstructured.Unstructured{Object: map[string]interface{}{}})).ToNot(Succeed())
+}
+
+func TestProbeController_NoTimeout(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	check := newProbeCheck("no-timeout", "http://127.0.0.1:0/healthz", "http://127.0.0.1:0/c1")
+	check.Spec.Timeout = nil
+	r := newProbeReconciler(t, check)
+
+	// Act
+	_, err := r.Reconcile(NewReconcileRequest("no-timeout", "default"))
+	g.Expect(err).ToNot(HaveOccurred())
+	_, err = r.Reconcile(NewReconcileRequest("no-timeout", "default"))
+	g.Expect(err).ToNot(HaveOccurred())
+
+	// Assert, the probe is not run and the missing timeout is reported once
+	actual := &monitoringv1alpha1.Check{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: "no-timeout", Namespace: "default"}, actual)).To(Succeed())
+	condition := monitoringv1alpha1.FindCondition(actual.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded)
+	g.Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(condition.Reason).To(Equal("NoTimeout"))
+	g.Expect(actual.Status.LastProbe).To(BeNil())
+	g.Expect(r.Recorder.(*record.FakeRecorder).Events).To(HaveLen(1))
+}
+
+func TestProbeController_ProbeRemoved(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	check := newProbeCheck("removed", "http://127.0.0.1:0/healthz", "http://127.0.0.1:0/c1")
+	check.Spec.Probe = nil
+	check.Status.Conditions = []monitoringv1alpha1.Condition{
+		{Type: monitoringv1alpha1.ConditionSynced, Status: metav1.ConditionTrue, Reason: "Synced"},
+		{Type: monitoringv1alpha1.ConditionProbeSucceeded, Status: metav1.ConditionFalse, Reason: "Failed"},
+	}
+	r := newProbeReconciler(t, check)
+
+	// Act
+	result, err := r.Reconcile(NewReconcileRequest("removed", "default"))
+
+	// Assert, the condition of the removed probe is removed as well
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(result).To(Equal(ctrl.Result{}))
+	actual := &monitoringv1alpha1.Check{}
+	g.Expect(r.Get(context.TODO(), types.NamespacedName{Name: "removed", Namespace: "default"}, actual)).To(Succeed())
+	g.Expect(monitoringv1alpha1.FindCondition(actual.Status.Conditions, monitoringv1alpha1.ConditionProbeSucceeded)).To(BeNil())
+	g.Expect(monitoringv1alpha1.IsConditionTrue(actual.Status.Conditions, monitoringv1alpha1.ConditionSynced)).To(BeTrue())
+}
+
+func newProbeCheck(name, url, pingURL string) *monitoringv1alpha1.Check {
+	timeout := int32(300)
+	return &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: monitoringv1alpha1.CheckSpec{
+			Timeout: &timeout,
+			Probe: &monitoringv1alpha1.Probe{
+				HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: url},
+			},
+		},
+		Status: monitoringv1alpha1.CheckStatus{PingURL: pingURL},
+	}
+}
+
+func newProbeReconciler(t *testing.T, objs ...runtime.Object) *ProbeReconciler {
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{})
+	now := metav1.NewTime(time.Date(2019, 11, 1, 12, 0, 0, 0, time.UTC))
+
+	return &ProbeReconciler{
+		Client:   fake.NewFakeClientWithScheme(s, objs...),
+		Log:      testutil.LogrTestLogger{T: t},
+		Recorder: record.NewFakeRecorder(100),
+		Pinger:   NewPinger(&http.Client{}),
+		Prober:   newLoopbackProber(),
+		Clock:    Clock{Source: func() *metav1.Time { return &now }},
+	}
+}
+
+// concurrentStatusClient writes the status of a Check once after it was first read, as the Check controller
+// would, and rejects status writes of a Check with an outdated resource version, as the API server does
+type concurrentStatusClient struct {
+	client.Client
+	write     func(check *monitoringv1alpha1.Check)
+	written   bool
+	conflicts int
+}
+
+func (c *concurrentStatusClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if err := c.Client.Get(ctx, key, obj); err != nil {
+		return err
+	}
+
+	if check, ok := obj.(*monitoringv1alpha1.Check); ok && !c.written {
+		c.written = true
+		current := check.DeepCopy()
+		c.write(current)
+		current.ResourceVersion = current.ResourceVersion + "1"
+		return c.Client.Status().Update(ctx, current)
+	}
+	return nil
+}
+
+func (c *concurrentStatusClient) Status() client.StatusWriter {
+	return c
+}
+
+func (c *concurrentStatusClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	check := obj.(*monitoringv1alpha1.Check)
+	current := &monitoringv1alpha1.Check{}
+	if err := c.Client.Get(ctx, types.NamespacedName{Name: check.Name, Namespace: check.Namespace}, current); err != nil {
+		return err
+	}
+	if current.ResourceVersion != check.ResourceVersion {
+		c.conflicts++
+		return apierrs.NewConflict(schema.GroupResource{Group: monitoringv1alpha1.GroupVersion.Group, Resource: "checks"}, check.Name, fmt.Errorf("the object has been modified"))
+	}
+
+	check.ResourceVersion = check.ResourceVersion + "1"
+	return c.Client.Status().Update(ctx, check, opts...)
+}
+
+func (c *concurrentStatusClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.Client.Status().Patch(ctx, obj, patch, opts...)
+}
@@ -0,0 +1,281 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"syscall"
+	"time"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// defaultProbeTimeout is used when the probe of a check has no timeout
+const defaultProbeTimeout = 10 * time.Second
+
+// maxProbeTimeout is the largest timeout of a probe
+const maxProbeTimeout = 60 * time.Second
+
+// maxProbeBodyBytes bounds the size of the body read when matching the response of an HTTP probe
+const maxProbeBodyBytes = 1 << 20
+
+// DefaultBlockedProbeNetworks are the loopback, link-local and unspecified networks, which hold the operator
+// itself and the metadata endpoints of cloud providers. Probes never connect to them, even when allowed.
+var DefaultBlockedProbeNetworks = []string{
+	"127.0.0.0/8",
+	"::1/128",
+	"169.254.0.0/16",
+	"fe80::/10",
+	"0.0.0.0/32",
+	"::/128",
+}
+
+// DefaultPrivateProbeNetworks are the private and shared networks, which hold the pods, services and nodes of
+// most clusters. Probes only connect to them when allowed, unlike public addresses.
+var DefaultPrivateProbeNetworks = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",
+	"fc00::/7",
+}
+
+// Prober runs the probes of checks
+type Prober struct {
+	HTTPClient *http.Client
+	Dialer     *net.Dialer
+	Resolver   *net.Resolver
+
+	// AllowedNetworks holds the private addresses probes connect to, e.g. the pod and service networks
+	AllowedNetworks []*net.IPNet
+
+	// PrivateNetworks holds the addresses probes only connect to when in AllowedNetworks, any other
+	// address is public
+	PrivateNetworks []*net.IPNet
+
+	// BlockedNetworks holds the addresses probes never connect to, even when in AllowedNetworks
+	BlockedNetworks []*net.IPNet
+}
+
+// ProbeError is returned when a probe fails. Reason describes the failure without the response or
+// connection details of the target, which are only logged, as probes run on behalf of anyone allowed
+// to create Checks.
+type ProbeError struct {
+	Reason string
+	Err    error
+}
+
+func (e *ProbeError) Error() string {
+	if e.Err == nil {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Reason, e.Err)
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// ProbeFailureReason returns the reason of a failed probe that may be shown on the Check
+func ProbeFailureReason(err error) string {
+	if pe, ok := err.(*ProbeError); ok {
+		return pe.Reason
+	}
+	return "probe failed"
+}
+
+// IsProbeNetworkNotAllowed returns true when a probe failed as its target is not in the networks probes
+// are allowed to connect to
+func IsProbeNetworkNotAllowed(err error) bool {
+	var notAllowed *notAllowedError
+	return errors.As(err, &notAllowed)
+}
+
+// NewProber creates a new Prober connecting to public addresses, blocking the DefaultBlockedProbeNetworks
+// and the DefaultPrivateProbeNetworks not in AllowedNetworks
+func NewProber() *Prober {
+	p := &Prober{
+		Resolver: net.DefaultResolver,
+	}
+	p.BlockedNetworks, _ = ParseNetworks(DefaultBlockedProbeNetworks)
+	p.PrivateNetworks, _ = ParseNetworks(DefaultPrivateProbeNetworks)
+
+	// the addresses are checked when connecting, after host names are resolved and for every redirect
+	p.Dialer = &net.Dialer{Control: p.control}
+	p.HTTPClient = &http.Client{
+		Timeout: maxProbeTimeout,
+		Transport: &http.Transport{
+			DialContext:         p.Dialer.DialContext,
+			TLSHandshakeTimeout: defaultProbeTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+	return p
+}
+
+// ParseNetworks parses a list of networks in CIDR notation
+func ParseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Allowed returns true when probes may connect to the ip
+func (p *Prober) Allowed(ip net.IP) bool {
+	if containsIP(p.BlockedNetworks, ip) {
+		return false
+	}
+	return containsIP(p.AllowedNetworks, ip) || !containsIP(p.PrivateNetworks, ip)
+}
+
+// control refuses connections to addresses probes are not allowed to connect to
+func (p *Prober) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !p.Allowed(ip) {
+		return &notAllowedError{host: host}
+	}
+
+	return nil
+}
+
+// notAllowedError is returned when connecting to an address probes are not allowed to connect to
+type notAllowedError struct {
+	host string
+}
+
+func (e *notAllowedError) Error() string {
+	return fmt.Sprintf("probes are not allowed to connect to %s", e.host)
+}
+
+// connectFailed returns the ProbeError of a failed connection, telling addresses probes are not allowed
+// to connect to apart from other failures
+func connectFailed(reason string, err error) error {
+	if IsProbeNetworkNotAllowed(err) {
+		return &ProbeError{Reason: "the target is not in the networks probes are allowed to connect to", Err: err}
+	}
+	return &ProbeError{Reason: reason, Err: err}
+}
+
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Probe runs the probe, returning an error describing why the probe failed
+func (p *Prober) Probe(probe monitoringv1alpha1.Probe) error {
+	timeout := defaultProbeTimeout
+	if probe.TimeoutSeconds != nil {
+		timeout = time.Duration(*probe.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	switch {
+	case probe.HTTPGet != nil:
+		return p.httpGet(ctx, *probe.HTTPGet)
+	case probe.TCPSocket != nil:
+		return p.tcpSocket(ctx, *probe.TCPSocket)
+	case probe.DNS != nil:
+		return p.dns(ctx, *probe.DNS)
+	}
+
+	return &ProbeError{Reason: "probe has no httpGet, tcpSocket or dns set"}
+}
+
+func (p *Prober) httpGet(ctx context.Context, probe monitoringv1alpha1.HTTPGetProbe) error {
+	req, err := http.NewRequest(http.MethodGet, probe.URL, nil)
+	if err != nil {
+		return &ProbeError{Reason: "invalid url", Err: err}
+	}
+
+	res, err := p.HTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return connectFailed("GET request failed", err)
+	}
+	defer res.Body.Close()
+
+	if probe.ExpectedStatus != nil {
+		if res.StatusCode != int(*probe.ExpectedStatus) {
+			return &ProbeError{Reason: "GET returned an unexpected status", Err: fmt.Errorf("GET %s returned status %s, expected %d", probe.URL, res.Status, *probe.ExpectedStatus)}
+		}
+	} else if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &ProbeError{Reason: "GET returned an unexpected status", Err: fmt.Errorf("GET %s returned status %s", probe.URL, res.Status)}
+	}
+
+	if probe.BodyMatch == "" {
+		return nil
+	}
+
+	re, err := regexp.Compile(probe.BodyMatch)
+	if err != nil {
+		return &ProbeError{Reason: "invalid bodyMatch", Err: err}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxProbeBodyBytes))
+	if err != nil {
+		return &ProbeError{Reason: "GET request failed", Err: err}
+	}
+
+	if !re.Match(body) {
+		return &ProbeError{Reason: "GET returned an unexpected body", Err: fmt.Errorf("GET %s returned a body not matching %s", probe.URL, probe.BodyMatch)}
+	}
+
+	return nil
+}
+
+func (p *Prober) tcpSocket(ctx context.Context, probe monitoringv1alpha1.TCPSocketProbe) error {
+	conn, err := p.Dialer.DialContext(ctx, "tcp", probe.Address)
+	if err != nil {
+		return connectFailed("TCP connect failed", err)
+	}
+	return conn.Close()
+}
+
+func (p *Prober) dns(ctx context.Context, probe monitoringv1alpha1.DNSProbe) error {
+	addrs, err := p.Resolver.LookupHost(ctx, probe.Host)
+	if err != nil {
+		return &ProbeError{Reason: "DNS lookup failed", Err: err}
+	}
+
+	if len(addrs) == 0 {
+		return &ProbeError{Reason: "DNS lookup failed", Err: fmt.Errorf("%s resolved to no addresses", probe.Host)}
+	}
+
+	return nil
+}
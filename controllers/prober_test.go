@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+func TestProber_HTTPGet(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/down" {
+			res.WriteHeader(http.StatusServiceUnavailable)
+		}
+		res.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+	prober := newLoopbackProber()
+	unavailable := int32(http.StatusServiceUnavailable)
+
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL}})).To(Succeed())
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL, BodyMatch: `"status":"ok"`}})).To(Succeed())
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL, BodyMatch: `"status":"error"`}})).ToNot(Succeed())
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL + "/down"}})).ToNot(Succeed())
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL + "/down", ExpectedStatus: &unavailable}})).To(Succeed())
+}
+
+func TestProber_TCPSocket(t *testing.T) {
+	g := NewGomegaWithT(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).ToNot(HaveOccurred())
+	address := listener.Addr().String()
+	prober := newLoopbackProber()
+
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{TCPSocket: &monitoringv1alpha1.TCPSocketProbe{Address: address}})).To(Succeed())
+	listener.Close()
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{TCPSocket: &monitoringv1alpha1.TCPSocketProbe{Address: address}})).ToNot(Succeed())
+}
+
+func TestProber_DNS(t *testing.T) {
+	g := NewGomegaWithT(t)
+	prober := NewProber()
+
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{DNS: &monitoringv1alpha1.DNSProbe{Host: "localhost"}})).To(Succeed())
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{DNS: &monitoringv1alpha1.DNSProbe{Host: "does-not-exist.invalid"}})).ToNot(Succeed())
+}
+
+func TestProber_NoProbe(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(NewProber().Probe(monitoringv1alpha1.Probe{})).ToNot(Succeed())
+}
+
+func TestProber_BlockedNetworks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+	prober := NewProber()
+	prober.AllowedNetworks, _ = ParseNetworks([]string{"127.0.0.0/8"})
+
+	err := prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL}})
+	g.Expect(err).To(MatchError(ContainSubstring("probes are not allowed to connect to 127.0.0.1")))
+	g.Expect(ProbeFailureReason(err)).To(Equal("the target is not in the networks probes are allowed to connect to"))
+	g.Expect(IsProbeNetworkNotAllowed(err)).To(BeTrue())
+	err = prober.Probe(monitoringv1alpha1.Probe{TCPSocket: &monitoringv1alpha1.TCPSocketProbe{Address: server.Listener.Addr().String()}})
+	g.Expect(err).To(MatchError(ContainSubstring("probes are not allowed to connect to 127.0.0.1")))
+}
+
+func TestProber_Allowed(t *testing.T) {
+	g := NewGomegaWithT(t)
+	prober := NewProber()
+
+	g.Expect(prober.Allowed(net.ParseIP("93.184.216.34"))).To(BeTrue())
+	g.Expect(prober.Allowed(net.ParseIP("2606:2800:220:1::248"))).To(BeTrue())
+	g.Expect(prober.Allowed(net.ParseIP("10.0.0.1"))).To(BeFalse())
+	g.Expect(prober.Allowed(net.ParseIP("fd00::1"))).To(BeFalse())
+	g.Expect(prober.Allowed(net.ParseIP("127.0.0.1"))).To(BeFalse())
+	g.Expect(prober.Allowed(net.ParseIP("169.254.169.254"))).To(BeFalse())
+
+	prober.AllowedNetworks, _ = ParseNetworks([]string{"10.244.0.0/16", "10.96.0.0/12", "169.254.0.0/16"})
+	g.Expect(prober.Allowed(net.ParseIP("10.244.1.5"))).To(BeTrue())
+	g.Expect(prober.Allowed(net.ParseIP("10.96.0.10"))).To(BeTrue())
+	g.Expect(prober.Allowed(net.ParseIP("10.0.0.1"))).To(BeFalse())
+	g.Expect(prober.Allowed(net.ParseIP("192.168.0.1"))).To(BeFalse())
+	g.Expect(prober.Allowed(net.ParseIP("93.184.216.34"))).To(BeTrue())
+	g.Expect(prober.Allowed(net.ParseIP("169.254.169.254"))).To(BeFalse())
+	g.Expect(prober.Allowed(net.ParseIP("::1"))).To(BeFalse())
+
+	_, err := ParseNetworks([]string{"10.0.0.1"})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestProber_FailureReason(t *testing.T) {
+	g := NewGomegaWithT(t)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write([]byte(`secret`))
+	}))
+	defer server.Close()
+	prober := newLoopbackProber()
+	internalError := int32(http.StatusInternalServerError)
+
+	err := prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL + "/internal"}})
+	g.Expect(err).To(MatchError(ContainSubstring("/internal")))
+	g.Expect(ProbeFailureReason(err)).To(Equal("GET returned an unexpected status"))
+	g.Expect(prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL, ExpectedStatus: &internalError, BodyMatch: "^s"}})).To(Succeed())
+	err = prober.Probe(monitoringv1alpha1.Probe{HTTPGet: &monitoringv1alpha1.HTTPGetProbe{URL: server.URL, ExpectedStatus: &internalError, BodyMatch: "^x"}})
+	g.Expect(ProbeFailureReason(err)).To(Equal("GET returned an unexpected body"))
+}
+
+// newLoopbackProber creates a Prober allowed to connect to the test servers on the loopback network
+func newLoopbackProber() *Prober {
+	prober := NewProber()
+	prober.AllowedNetworks, _ = ParseNetworks([]string{"127.0.0.0/8"})
+	prober.BlockedNetworks = nil
+	return prober
+}
@@ -0,0 +1,143 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+// minSelfCheckTimeout is the smallest timeout and grace period accepted by healthchecks.io
+const minSelfCheckTimeout = time.Minute
+
+var _ manager.Runnable = &SelfCheck{}
+var _ manager.LeaderElectionRunnable = &SelfCheck{}
+
+// SelfCheck creates a check in healthchecks.io for the operator itself and pings it every interval
+// while the operator is the leader and able to read Checks, alerting when the operator or its cluster dies
+type SelfCheck struct {
+	Client       client.Reader
+	Log          logr.Logger
+	Hckio        HealthchecksAPI
+	Pinger       *Pinger
+	Interval     time.Duration
+	Name         string
+	Schedule     string
+	Channels     []string
+	NamePrefix   string
+	OwnershipTag string
+
+	pingURL string
+}
+
+// Start pings the self check every interval until stop is closed
+func (s *SelfCheck) Start(stop <-chan struct{}) error {
+	wait.Until(func() {
+		if err := s.Heartbeat(context.Background()); err != nil {
+			s.Log.Error(err, "unable to ping the self check")
+		}
+	}, s.Interval, stop)
+
+	return nil
+}
+
+// NeedLeaderElection ensures only the leading replica of the operator pings the self check
+func (s *SelfCheck) NeedLeaderElection() bool {
+	return true
+}
+
+// Heartbeat creates the self check when needed and pings it when the operator is able to read Checks
+func (s *SelfCheck) Heartbeat(ctx context.Context) error {
+	var checks monitoringv1alpha1.CheckList
+	if err := s.Client.List(ctx, &checks); err != nil {
+		return fmt.Errorf("unable to list Checks, skipping ping: %s", err)
+	}
+
+	if s.pingURL == "" {
+		healthcheck, err := s.ensure()
+		if err != nil {
+			return err
+		}
+		s.Log.V(0).Info(fmt.Sprintf("created/updated self check: %s", healthcheck.ID()), "name", healthcheck.Name)
+		s.pingURL = healthcheck.PingURL
+	}
+
+	if err := s.Pinger.Ping(ClientOptions{}, s.pingURL, nil); err != nil {
+		// the self check may have been deleted, it is created again on the next heartbeat
+		s.pingURL = ""
+		return err
+	}
+	s.Log.V(1).Info("pinged the self check")
+
+	return nil
+}
+
+// ensure creates or updates the self check in healthchecks.io
+func (s *SelfCheck) ensure() (*healthchecksio.HealthcheckResponse, error) {
+	channels := make([]string, 0)
+	if len(s.Channels) > 0 {
+		allChannels, err := s.Hckio.GetAllChannels()
+		if err != nil {
+			return nil, err
+		}
+		check := monitoringv1alpha1.Check{Spec: monitoringv1alpha1.CheckSpec{Channels: s.Channels}}
+		channels = matchTargetChannels(check, allChannels...)
+	}
+
+	return s.Hckio.Create(s.healthcheck(channels...))
+}
+
+// healthcheck returns the desired self check, expecting a ping every interval unless a schedule is set
+func (s *SelfCheck) healthcheck(channels ...string) healthchecksio.Healthcheck {
+	period := s.Interval
+	if period < minSelfCheckTimeout {
+		period = minSelfCheckTimeout
+	}
+
+	hc := healthchecksio.Healthcheck{
+		Name:     s.HealthcheckName(),
+		Schedule: s.Schedule,
+		Grace:    int(period.Seconds()),
+		Channels: strings.Join(channels, ","),
+		Unique:   []string{"name"},
+	}
+	if s.Schedule == "" {
+		hc.Timeout = int(period.Seconds())
+	}
+	if s.OwnershipTag != "" {
+		hc.Tags = s.OwnershipTag
+	}
+
+	return hc
+}
+
+// HealthcheckName returns the name of the self check in healthchecks.io
+func (s *SelfCheck) HealthcheckName() string {
+	if s.NamePrefix != "" {
+		return fmt.Sprintf("%s/%s", s.NamePrefix, s.Name)
+	}
+	return s.Name
+}
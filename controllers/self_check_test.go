@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+func TestSelfCheck_Healthcheck(t *testing.T) {
+	g := NewGomegaWithT(t)
+	s := &SelfCheck{Interval: 30 * time.Second, Name: "healthchecksio-operator", NamePrefix: "cluster-1", OwnershipTag: "k8s-operator"}
+
+	hc := s.healthcheck("channel-1")
+	g.Expect(hc.Name).To(Equal("cluster-1/healthchecksio-operator"))
+	g.Expect(hc.Timeout).To(Equal(60))
+	g.Expect(hc.Grace).To(Equal(60))
+	g.Expect(hc.Tags).To(Equal("k8s-operator"))
+	g.Expect(hc.Channels).To(Equal("channel-1"))
+
+	s.Interval = 5 * time.Minute
+	s.Schedule = "*/5 * * * *"
+	hc = s.healthcheck()
+	g.Expect(hc.Schedule).To(Equal("*/5 * * * *"))
+	g.Expect(hc.Timeout).To(Equal(0))
+	g.Expect(hc.Grace).To(Equal(300))
+}
+
+func TestSelfCheck_Heartbeat(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	requests := make([]string, 0)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch req.URL.Path {
+		case "/channels/":
+			res.Write([]byte(`{"channels": [{"id": "c1", "name": "Ops", "kind": "slack"}, {"id": "c2", "name": "Me", "kind": "email"}]}`))
+		case "/checks/":
+			body, _ := ioutil.ReadAll(req.Body)
+			var hc map[string]interface{}
+			json.Unmarshal(body, &hc)
+			g.Expect(hc["channels"]).To(Equal("c1"))
+			res.Write([]byte(`{"name": "healthchecksio-operator", "ping_url": "` + server.URL + `/ping/1", "update_url": "` + server.URL + `/checks/1"}`))
+		}
+	}))
+	defer server.Close()
+
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{}, &monitoringv1alpha1.CheckList{})
+	selfCheck := &SelfCheck{
+		Client:   fake.NewFakeClientWithScheme(s),
+		Log:      testutil.LogrTestLogger{T: t},
+		Hckio:    NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", server.URL)),
+		Pinger:   NewPinger(&http.Client{}),
+		Interval: time.Minute,
+		Name:     "healthchecksio-operator",
+		Channels: []string{"slack/Ops"},
+	}
+
+	// Act
+	g.Expect(selfCheck.Heartbeat(context.TODO())).To(Succeed())
+	g.Expect(selfCheck.Heartbeat(context.TODO())).To(Succeed())
+
+	// Assert, the self check is created once and pinged on every heartbeat
+	g.Expect(requests).To(Equal([]string{"GET /channels/", "POST /checks/", "POST /ping/1", "POST /ping/1"}))
+}
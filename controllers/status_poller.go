@@ -0,0 +1,267 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+)
+
+// pollerAPIExpiry is the number of intervals an API is polled for after it was last used
+const pollerAPIExpiry = 3
+
+// pollerStateExpiry is the number of intervals the polled state is served for, leaving the next poll
+// time to finish, afterwards the reconcilers call the API themselves
+const pollerStateExpiry = 2
+
+var _ manager.Runnable = &StatusPoller{}
+var _ manager.LeaderElectionRunnable = &StatusPoller{}
+
+// StatusPoller fetches all checks and channels once per interval for every API used by the
+// reconcilers, so the status of a Check is refreshed without an API call per Check
+type StatusPoller struct {
+	Log      logr.Logger
+	Clock    Clock
+	Interval time.Duration
+
+	mu   sync.RWMutex
+	apis map[string]*polledAPI
+}
+
+// polledAPI holds the checks and channels last fetched from an API
+type polledAPI struct {
+	api      HealthchecksAPI
+	lastUsed time.Time
+	polledAt time.Time
+	checks   map[string]*healthchecksio.HealthcheckResponse
+	channels []*healthchecksio.HealthcheckChannelResponse
+
+	// generation is increased by every write, written holds the generation and time a check was
+	// last stored or invalidated at, so a poll started before the write does not undo it
+	generation uint64
+	written    map[string]polledWrite
+}
+
+// polledWrite records a check stored or invalidated
+type polledWrite struct {
+	generation uint64
+	at         time.Time
+}
+
+// write records that the check with the id was stored or invalidated
+func (polled *polledAPI) write(id string, now time.Time) {
+	polled.generation++
+	polled.written[id] = polledWrite{generation: polled.generation, at: now}
+}
+
+// NewStatusPoller creates a new StatusPoller polling every interval
+func NewStatusPoller(log logr.Logger, interval time.Duration) *StatusPoller {
+	return &StatusPoller{
+		Log:      log,
+		Clock:    NewClock(),
+		Interval: interval,
+	}
+}
+
+// Start polls every interval until stop is closed
+func (p *StatusPoller) Start(stop <-chan struct{}) error {
+	wait.Until(p.Poll, p.Interval, stop)
+	return nil
+}
+
+// NeedLeaderElection ensures the poller only runs in the replica running the controllers
+func (p *StatusPoller) NeedLeaderElection() bool {
+	return true
+}
+
+// Poll fetches the checks and channels of every API used since the last few intervals
+func (p *StatusPoller) Poll() {
+	now := p.Clock.Now().Time
+
+	p.mu.Lock()
+	apis := make([]HealthchecksAPI, 0, len(p.apis))
+	started := make(map[string]uint64, len(p.apis))
+	for key, polled := range p.apis {
+		if now.Sub(polled.lastUsed) > pollerAPIExpiry*p.Interval {
+			delete(p.apis, key)
+			continue
+		}
+		apis = append(apis, polled.api)
+		started[key] = polled.generation
+	}
+	p.mu.Unlock()
+
+	for _, api := range apis {
+		healthchecks, err := api.GetAll()
+		if err != nil {
+			p.Log.Error(err, "unable to poll checks from healthchecksio")
+			p.expire(api)
+			continue
+		}
+
+		channels, err := api.GetAllChannels()
+		if err != nil {
+			p.Log.Error(err, "unable to poll channels from healthchecksio")
+			p.expire(api)
+			continue
+		}
+
+		checks := make(map[string]*healthchecksio.HealthcheckResponse, len(healthchecks))
+		for _, hc := range healthchecks {
+			checks[hc.ID()] = hc
+		}
+
+		p.mu.Lock()
+		if polled, ok := p.apis[api.Key()]; ok {
+			// checks stored or invalidated while polling keep their state, which is newer than the poll
+			for id, written := range polled.written {
+				if written.generation <= started[api.Key()] {
+					delete(polled.written, id)
+					continue
+				}
+				if hc, ok := polled.checks[id]; ok {
+					checks[id] = hc
+				} else {
+					delete(checks, id)
+				}
+			}
+			polled.checks = checks
+			polled.channels = channels
+			polled.polledAt = now
+		}
+		p.mu.Unlock()
+		p.Log.V(1).Info("polled checks from healthchecksio", "checks", len(checks), "channels", len(channels))
+	}
+}
+
+// Check returns the last polled state of the check with the id, registering the API to be polled.
+// Nothing is returned when the state is stale, because the last poll failed or is too old.
+func (p *StatusPoller) Check(api HealthchecksAPI, id string) (*healthchecksio.HealthcheckResponse, bool) {
+	polled := p.use(api)
+	now := p.Clock.Now().Time
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	updated := polled.polledAt
+	if written, ok := polled.written[id]; ok && written.at.After(updated) {
+		updated = written.at
+	}
+	if p.stale(updated, now) {
+		return nil, false
+	}
+	hc, ok := polled.checks[id]
+	return hc, ok
+}
+
+// Channels returns the last polled channels, registering the API to be polled.
+// Nothing is returned when the channels are stale, because the last poll failed or is too old.
+func (p *StatusPoller) Channels(api HealthchecksAPI) ([]*healthchecksio.HealthcheckChannelResponse, bool) {
+	polled := p.use(api)
+	now := p.Clock.Now().Time
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stale(polled.polledAt, now) {
+		return nil, false
+	}
+	return polled.channels, polled.channels != nil
+}
+
+// Count returns the number of checks of the API as last polled, registering the API to be polled.
+// Nothing is returned when the checks are stale, because the last poll failed or is too old.
+func (p *StatusPoller) Count(api HealthchecksAPI) (int, bool) {
+	polled := p.use(api)
+	now := p.Clock.Now().Time
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.stale(polled.polledAt, now) {
+		return 0, false
+	}
+
+	// invalidated checks are only forgotten until the next poll, they still exist
+	count := len(polled.checks)
+	for id := range polled.written {
+		if _, ok := polled.checks[id]; !ok {
+			count++
+		}
+	}
+	return count, true
+}
+
+// Store records the state of a check returned by a write, until the next poll
+func (p *StatusPoller) Store(api HealthchecksAPI, hc *healthchecksio.HealthcheckResponse) {
+	polled := p.use(api)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	polled.checks[hc.ID()] = hc
+	polled.write(hc.ID(), p.Clock.Now().Time)
+}
+
+// Invalidate forgets the polled state of the check with the id, until the next poll
+func (p *StatusPoller) Invalidate(id string) {
+	now := p.Clock.Now().Time
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, polled := range p.apis {
+		delete(polled.checks, id)
+		polled.write(id, now)
+	}
+}
+
+// expire marks the polled state of an API as stale after a failed poll, so the reconcilers call the
+// API themselves and report it unavailable instead of serving the state from before the failure
+func (p *StatusPoller) expire(api HealthchecksAPI) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if polled, ok := p.apis[api.Key()]; ok {
+		polled.polledAt = time.Time{}
+	}
+}
+
+// stale returns true when state updated at the given time is no longer served
+func (p *StatusPoller) stale(updated time.Time, now time.Time) bool {
+	return updated.IsZero() || now.Sub(updated) > pollerStateExpiry*p.Interval
+}
+
+func (p *StatusPoller) use(api HealthchecksAPI) *polledAPI {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.apis == nil {
+		p.apis = make(map[string]*polledAPI)
+	}
+
+	key := api.Key()
+	polled, ok := p.apis[key]
+	if !ok {
+		polled = &polledAPI{api: api, checks: make(map[string]*healthchecksio.HealthcheckResponse), written: make(map[string]polledWrite)}
+		p.apis[key] = polled
+	}
+	polled.lastUsed = p.Clock.Now().Time
+
+	return polled
+}
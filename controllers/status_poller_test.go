@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/gomega"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+	"github.com/kristofferahl/healthchecksio-operator/testutil"
+)
+
+const pollerChecksResponse = `{
+	"checks": [
+		{
+			"name": "default/foo",
+			"status": "up",
+			"update_url": "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776"
+		}
+	]
+}`
+
+const pollerChannelsResponse = `{
+	"channels": [
+		{"id": "4ec5a071-2d08-4baa-898a-eb4eb3cd6941", "name": "My Work Email", "kind": "email"}
+	]
+}`
+
+func TestStatusPoller_Poll(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	server, requests := newStatusPollerServer()
+	defer server.Close()
+
+	now := metav1.Now()
+	poller := NewStatusPoller(testutil.LogrTestLogger{T: t}, time.Minute)
+	poller.Clock = Clock{Source: func() *metav1.Time { return &now }}
+	api := NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", server.URL))
+
+	// Act & assert, nothing is known about an API before it is polled
+	_, ok := poller.Check(api, "e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(ok).To(BeFalse())
+	_, ok = poller.Channels(api)
+	g.Expect(ok).To(BeFalse())
+
+	// Act & assert, the checks and channels of used APIs are polled
+	poller.Poll()
+	hc, ok := poller.Check(api, "e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hc.Status).To(Equal("up"))
+	channels, ok := poller.Channels(api)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(channels).To(HaveLen(1))
+	g.Expect(*requests).To(Equal([]string{"/checks/", "/channels/"}))
+
+	count, ok := poller.Count(api)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(count).To(Equal(1))
+
+	// Act & assert, APIs no longer used are not polled
+	now = metav1.NewTime(now.Add(time.Hour))
+	poller.Poll()
+	g.Expect(*requests).To(HaveLen(2))
+}
+
+func TestStatusPoller_Stale(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch {
+		case failing:
+			res.WriteHeader(http.StatusServiceUnavailable)
+		case req.URL.Path == "/channels/":
+			res.Write([]byte(pollerChannelsResponse))
+		default:
+			res.Write([]byte(pollerChecksResponse))
+		}
+	}))
+	defer server.Close()
+
+	now := metav1.Now()
+	poller := NewStatusPoller(testutil.LogrTestLogger{T: t}, time.Minute)
+	poller.Clock = Clock{Source: func() *metav1.Time { return &now }}
+	api := NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", server.URL))
+	id := "e71024f4-8537-4dd2-b742-ebe5a1685776"
+	poller.Channels(api)
+	poller.Poll()
+
+	// Act & assert, the polled state is not served once it is older than two intervals
+	now = metav1.NewTime(now.Add(3 * time.Minute))
+	_, ok := poller.Check(api, id)
+	g.Expect(ok).To(BeFalse())
+	_, ok = poller.Channels(api)
+	g.Expect(ok).To(BeFalse())
+
+	// Act & assert, a stored check is served until it is as old
+	poller.Store(api, &healthchecksio.HealthcheckResponse{Status: "paused", UpdateURL: "https://healthchecks.io/api/v1/checks/" + id})
+	_, ok = poller.Check(api, id)
+	g.Expect(ok).To(BeTrue())
+
+	// Act & assert, the polled state is not served after a failed poll
+	poller.Poll()
+	failing = true
+	poller.Poll()
+	_, ok = poller.Check(api, id)
+	g.Expect(ok).To(BeFalse())
+	_, ok = poller.Channels(api)
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestStatusPoller_Store(t *testing.T) {
+	g := NewGomegaWithT(t)
+	poller := NewStatusPoller(testutil.LogrTestLogger{T: t}, time.Minute)
+	api := NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", ""))
+
+	poller.Store(api, &healthchecksio.HealthcheckResponse{
+		Status:    "new",
+		UpdateURL: "https://healthchecks.io/api/v1/checks/e71024f4-8537-4dd2-b742-ebe5a1685776",
+	})
+
+	hc, ok := poller.Check(api, "e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hc.Status).To(Equal("new"))
+
+	poller.Invalidate("e71024f4-8537-4dd2-b742-ebe5a1685776")
+	_, ok = poller.Check(api, "e71024f4-8537-4dd2-b742-ebe5a1685776")
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestStatusPoller_WriteDuringPoll(t *testing.T) {
+	// Arrange
+	g := NewGomegaWithT(t)
+	poller := NewStatusPoller(testutil.LogrTestLogger{T: t}, time.Minute)
+	var duringPoll func()
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/channels/" {
+			res.Write([]byte(pollerChannelsResponse))
+			return
+		}
+		if duringPoll != nil {
+			duringPoll()
+		}
+		res.Write([]byte(pollerChecksResponse))
+	}))
+	defer server.Close()
+	api := NewHealthchecksioAPI(testutil.NewTestHealthchecksioClient(t, "api-key", server.URL))
+	id := "e71024f4-8537-4dd2-b742-ebe5a1685776"
+	poller.Channels(api)
+
+	// Act & assert, a check stored while polling keeps the stored state
+	duringPoll = func() {
+		poller.Store(api, &healthchecksio.HealthcheckResponse{Status: "paused", UpdateURL: "https://healthchecks.io/api/v1/checks/" + id})
+	}
+	poller.Poll()
+	hc, ok := poller.Check(api, id)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hc.Status).To(Equal("paused"))
+
+	// Act & assert, a check invalidated while polling stays invalidated
+	duringPoll = func() { poller.Invalidate(id) }
+	poller.Poll()
+	_, ok = poller.Check(api, id)
+	g.Expect(ok).To(BeFalse())
+
+	// Act & assert, the next poll refreshes the check again
+	duringPoll = nil
+	poller.Poll()
+	hc, ok = poller.Check(api, id)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(hc.Status).To(Equal("up"))
+}
+
+func newStatusPollerServer() (*httptest.Server, *[]string) {
+	requests := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req.URL.Path)
+		if req.URL.Path == "/channels/" {
+			res.Write([]byte(pollerChannelsResponse))
+			return
+		}
+		res.Write([]byte(pollerChecksResponse))
+	}))
+	return server, &requests
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package controllers
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -40,6 +41,14 @@ var k8sClient client.Client
 var testEnv *envtest.Environment
 
 func TestAPIs(t *testing.T) {
+	if missing := missingControlPlaneAssets(); len(missing) > 0 {
+		// CI has to run the suite, install the binaries with make envtest
+		if os.Getenv("CI") != "" {
+			t.Fatalf("the envtest suite requires %v, run make envtest", missing)
+		}
+		t.Skipf("skipping the envtest suite, missing %v, see the development section of the README", missing)
+	}
+
 	RegisterFailHandler(Fail)
 
 	RunSpecsWithDefaultAndCustomReporters(t,
@@ -47,6 +56,34 @@ func TestAPIs(t *testing.T) {
 		[]Reporter{envtest.NewlineReporter{}})
 }
 
+// missingControlPlaneAssets returns the control plane binaries envtest starts that do not exist,
+// none when the suite runs against an existing cluster
+func missingControlPlaneAssets() []string {
+	if os.Getenv("USE_EXISTING_CLUSTER") == "true" {
+		return nil
+	}
+
+	dir := os.Getenv("KUBEBUILDER_ASSETS")
+	if dir == "" {
+		dir = "/usr/local/kubebuilder/bin"
+	}
+
+	var missing []string
+	for _, asset := range []struct{ name, env string }{
+		{name: "etcd", env: "TEST_ASSET_ETCD"},
+		{name: "kube-apiserver", env: "TEST_ASSET_KUBE_APISERVER"},
+	} {
+		path := os.Getenv(asset.env)
+		if path == "" {
+			path = filepath.Join(dir, asset.name)
+		}
+		if _, err := os.Stat(path); err != nil {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
 var _ = BeforeSuite(func(done Done) {
 	logf.SetLogger(zap.LoggerTo(GinkgoWriter, true))
 
@@ -16,9 +16,13 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
@@ -27,12 +31,18 @@ import (
 	healthchecksio "github.com/kristofferahl/go-healthchecksio"
 	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
 	"github.com/kristofferahl/healthchecksio-operator/controllers"
+	"github.com/kristofferahl/healthchecksio-operator/webhooks"
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	logrzap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -50,28 +60,100 @@ func init() {
 
 func main() {
 	var apiKey string
+	var apiURL string
+	var caBundle string
+	var insecureSkipVerify bool
+	var httpTimeout time.Duration
 	var metricsAddr string
 	var enableLeaderElection bool
+	var enableWebhooks bool
 	var development bool
 	var logLevel string
 	var namePrefix string
+	var ownershipTag string
+	var deletionPolicy string
 	var reconcileInterval time.Duration
+	var gcInterval time.Duration
+	var gcGracePeriod time.Duration
+	var gcDelete bool
+	var gcDryRun bool
+	var selfCheckName string
+	var selfCheckSchedule string
+	var selfCheckChannels string
+	var notificationAddr string
+	var notificationSecret string
+	var apiQPS float64
+	var apiBurst int
+	var apiFailureThreshold int
+	var apiCircuitOpenDuration time.Duration
+	var probeWorkers int
+	var probeAllowedNetworks string
+	var projectAllowedBaseURLs string
+	var projectAllowInsecure bool
 
+	flag.StringVar(&apiURL, "api-url", "https://healthchecks.io/api/v1", "The base URL of the healthchecks.io API. Set it to manage checks of a self-hosted instance.")
+	flag.StringVar(&caBundle, "ca-bundle", "", "Path to a PEM encoded CA bundle used to verify the healthchecks.io API.")
+	flag.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Skip verification of the certificate of the healthchecks.io API.")
+	flag.DurationVar(&httpTimeout, "http-timeout", 30*time.Second, "The timeout of requests to the healthchecks.io API and of pings sent by the operator.")
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Enable the admission webhooks. Requires serving certificates for the webhook server.")
 	flag.BoolVar(&development, "development", false, "Run the operator in development mode.")
 	flag.StringVar(&logLevel, "log-level", "info", "The log level used by the operator.")
 	flag.StringVar(&namePrefix, "name-prefix", "", "Prefix used to create unique resources across clusters.")
+	flag.StringVar(&ownershipTag, "ownership-tag", "", "Tag added to every check managed by the operator. Removed when a check is retained on deletion.")
+	flag.StringVar(&deletionPolicy, "deletion-policy", monitoringv1alpha1.DeletionPolicyDelete, "The default deletion policy of checks, Delete or Retain.")
 	flag.DurationVar(&reconcileInterval, "reconcile-interval", 1*time.Minute, "The interval for the reconcile loop")
+	flag.DurationVar(&gcInterval, "gc-interval", 1*time.Hour, "The interval for finding orphaned checks in healthchecks.io. Set it to 0 to disable the garbage collector.")
+	flag.DurationVar(&gcGracePeriod, "gc-grace-period", 24*time.Hour, "How long a check has to be orphaned before it is deleted.")
+	flag.BoolVar(&gcDelete, "gc-delete", false, "Delete orphaned checks from healthchecks.io once the grace period has passed.")
+	flag.BoolVar(&gcDryRun, "gc-dry-run", false, "Log the orphaned checks that would be deleted without deleting them.")
+	flag.StringVar(&selfCheckName, "self-check-name", "", "Name of a check in healthchecks.io the operator creates for itself and pings every reconcile interval. Empty disables the self check.")
+	flag.StringVar(&selfCheckSchedule, "self-check-schedule", "", "The schedule of the self check in Cron format. Defaults to expecting a ping every reconcile interval.")
+	flag.StringVar(&selfCheckChannels, "self-check-channels", "", "Comma separated list of channels, in the format kind or kind/name, notified when the self check goes down.")
+	flag.StringVar(&notificationAddr, "notification-addr", "", "The address the endpoint receiving notifications from a healthchecks.io webhook integration binds to. Empty disables the endpoint.")
+	flag.StringVar(&notificationSecret, "notification-secret", "", "The shared secret notifications from healthchecks.io are authenticated with. Prefer setting it through the environment.")
+	flag.Float64Var(&apiQPS, "api-qps", 1, "The number of requests per second sent to the healthchecks.io API, shared by every API key.")
+	flag.IntVar(&apiBurst, "api-burst", 10, "The number of requests sent to the healthchecks.io API in a burst, above api-qps.")
+	flag.IntVar(&apiFailureThreshold, "api-failure-threshold", 5, "The number of consecutive failed requests opening the circuit of the healthchecks.io API. Set it to 0 to never open it.")
+	flag.DurationVar(&apiCircuitOpenDuration, "api-circuit-open-duration", 1*time.Minute, "How long requests to the healthchecks.io API fail fast once its circuit is open.")
+	flag.IntVar(&probeWorkers, "probe-workers", 10, "The number of probes run at the same time.")
+	flag.StringVar(&probeAllowedNetworks, "probe-allowed-networks", "", "Comma separated list of private networks in CIDR notation probes may connect to, e.g. the pod and service networks. Probes connect to public addresses only when empty, loopback, link-local and API server addresses are never allowed.")
+	flag.StringVar(&projectAllowedBaseURLs, "project-allowed-base-urls", "", "Comma separated list of base URLs a HealthchecksProject may set, its API key is sent to it. HealthchecksProjects may only use the API of the operator when empty.")
+	flag.BoolVar(&projectAllowInsecure, "project-allow-insecure", false, "Allow HealthchecksProjects to skip verification of the certificate of their API.")
 	flag.Parse()
 
 	apiKey = envOrDefaultString("HEALTHCHECKSIO_API_KEY", "")
+	apiURL = envOrDefaultString("OPERATOR_API_URL", apiURL)
+	caBundle = envOrDefaultString("OPERATOR_CA_BUNDLE", caBundle)
+	insecureSkipVerify = envOrDefaultBool("OPERATOR_INSECURE_SKIP_VERIFY", insecureSkipVerify)
+	httpTimeout = envOrDefaultDuration("OPERATOR_HTTP_TIMEOUT", httpTimeout)
 	metricsAddr = envOrDefaultString("OPERATOR_METRICS_ADDR", metricsAddr)
 	enableLeaderElection = envOrDefaultBool("OPERATOR_ENABLE_LEADER_ELECTION", enableLeaderElection)
+	enableWebhooks = envOrDefaultBool("OPERATOR_ENABLE_WEBHOOKS", enableWebhooks)
 	development = envOrDefaultBool("OPERATOR_DEVELOPMENT", development)
 	logLevel = envOrDefaultString("OPERATOR_LOG_LEVEL", logLevel)
 	namePrefix = envOrDefaultString("OPERATOR_NAME_PREFIX", namePrefix)
+	ownershipTag = envOrDefaultString("OPERATOR_OWNERSHIP_TAG", ownershipTag)
+	deletionPolicy = envOrDefaultString("OPERATOR_DELETION_POLICY", deletionPolicy)
 	reconcileInterval = envOrDefaultDuration("OPERATOR_RECONCILE_INTERVAL", reconcileInterval)
+	gcInterval = envOrDefaultDuration("OPERATOR_GC_INTERVAL", gcInterval)
+	gcGracePeriod = envOrDefaultDuration("OPERATOR_GC_GRACE_PERIOD", gcGracePeriod)
+	gcDelete = envOrDefaultBool("OPERATOR_GC_DELETE", gcDelete)
+	gcDryRun = envOrDefaultBool("OPERATOR_GC_DRY_RUN", gcDryRun)
+	selfCheckName = envOrDefaultString("OPERATOR_SELF_CHECK_NAME", selfCheckName)
+	selfCheckSchedule = envOrDefaultString("OPERATOR_SELF_CHECK_SCHEDULE", selfCheckSchedule)
+	selfCheckChannels = envOrDefaultString("OPERATOR_SELF_CHECK_CHANNELS", selfCheckChannels)
+	notificationAddr = envOrDefaultString("OPERATOR_NOTIFICATION_ADDR", notificationAddr)
+	notificationSecret = envOrDefaultString("OPERATOR_NOTIFICATION_SECRET", notificationSecret)
+	apiQPS = envOrDefaultFloat("OPERATOR_API_QPS", apiQPS)
+	apiBurst = envOrDefaultInt("OPERATOR_API_BURST", apiBurst)
+	apiFailureThreshold = envOrDefaultInt("OPERATOR_API_FAILURE_THRESHOLD", apiFailureThreshold)
+	apiCircuitOpenDuration = envOrDefaultDuration("OPERATOR_API_CIRCUIT_OPEN_DURATION", apiCircuitOpenDuration)
+	probeWorkers = envOrDefaultInt("OPERATOR_PROBE_WORKERS", probeWorkers)
+	probeAllowedNetworks = envOrDefaultString("OPERATOR_PROBE_ALLOWED_NETWORKS", probeAllowedNetworks)
+	projectAllowedBaseURLs = envOrDefaultString("OPERATOR_PROJECT_ALLOWED_BASE_URLS", projectAllowedBaseURLs)
+	projectAllowInsecure = envOrDefaultBool("OPERATOR_PROJECT_ALLOW_INSECURE", projectAllowInsecure)
 
 	ctrl.SetLogger(logrzap.New(func(o *logrzap.Options) {
 		o.Development = development
@@ -85,14 +167,69 @@ func main() {
 
 	setupLog.Info(
 		"configuration",
+		"apiURL", apiURL,
+		"caBundle", caBundle,
+		"insecureSkipVerify", insecureSkipVerify,
+		"httpTimeout", httpTimeout,
 		"metricsAddr", metricsAddr,
 		"enableLeaderElection", enableLeaderElection,
+		"enableWebhooks", enableWebhooks,
 		"development", development,
 		"logLevel", logLevel,
 		"namePrefix", namePrefix,
+		"ownershipTag", ownershipTag,
+		"deletionPolicy", deletionPolicy,
 		"reconcileInterval", reconcileInterval,
+		"gcInterval", gcInterval,
+		"gcGracePeriod", gcGracePeriod,
+		"gcDelete", gcDelete,
+		"gcDryRun", gcDryRun,
+		"selfCheckName", selfCheckName,
+		"selfCheckSchedule", selfCheckSchedule,
+		"selfCheckChannels", selfCheckChannels,
+		"notificationAddr", notificationAddr,
+		"notificationSecretSet", notificationSecret != "",
+		"apiQPS", apiQPS,
+		"apiBurst", apiBurst,
+		"apiFailureThreshold", apiFailureThreshold,
+		"apiCircuitOpenDuration", apiCircuitOpenDuration,
+		"probeWorkers", probeWorkers,
+		"probeAllowedNetworks", probeAllowedNetworks,
+		"projectAllowedBaseURLs", projectAllowedBaseURLs,
+		"projectAllowInsecure", projectAllowInsecure,
 	)
 
+	if deletionPolicy != monitoringv1alpha1.DeletionPolicyDelete && deletionPolicy != monitoringv1alpha1.DeletionPolicyRetain {
+		setupLog.Error(fmt.Errorf("unsupported deletion policy %s", deletionPolicy), "invalid configuration")
+		os.Exit(1)
+	}
+
+	if apiQPS <= 0 || apiBurst < 1 {
+		setupLog.Error(fmt.Errorf("api-qps must be greater than 0 and api-burst at least 1"), "invalid configuration")
+		os.Exit(1)
+	}
+
+	if httpTimeout <= 0 {
+		setupLog.Error(fmt.Errorf("http-timeout must be greater than 0"), "invalid configuration")
+		os.Exit(1)
+	}
+
+	if probeWorkers < 1 {
+		setupLog.Error(fmt.Errorf("probe-workers must be at least 1"), "invalid configuration")
+		os.Exit(1)
+	}
+
+	prober, err := newProber(probeAllowedNetworks)
+	if err != nil {
+		setupLog.Error(err, "invalid configuration")
+		os.Exit(1)
+	}
+
+	if notificationAddr != "" && notificationSecret == "" {
+		setupLog.Error(fmt.Errorf("notification-secret is required when notification-addr is set"), "invalid configuration")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
@@ -104,23 +241,221 @@ func main() {
 		os.Exit(1)
 	}
 
-	hckioClient := healthchecksio.NewClient(apiKey)
-	hckioClient.Log = &logrLogger{
-		log: ctrl.Log.WithName("hckio-client"),
+	httpClient, err := newHTTPClient(caBundle, insecureSkipVerify, httpTimeout)
+	if err != nil {
+		setupLog.Error(err, "unable to create http client for the healthchecks.io API")
+		os.Exit(1)
+	}
+
+	hckioClients := controllers.NewClientCache(func(apiKey string) *healthchecksio.Client {
+		client := healthchecksio.NewClient(apiKey)
+		client.BaseURL = apiURL
+		client.HTTPClient = httpClient
+		client.Log = &logrLogger{
+			log: ctrl.Log.WithName("hckio-client"),
+		}
+		return client
+	})
+	apiLimiter := controllers.NewAPILimiter(float32(apiQPS), apiBurst, apiFailureThreshold, apiCircuitOpenDuration)
+	hckioClients.Limiter = apiLimiter
+	hckioClients.Instrument = true
+	hckioClients.ProjectPolicy = controllers.NewProjectPolicy(projectAllowedBaseURLs)
+	hckioClients.ProjectPolicy.AllowInsecureSkipVerify = projectAllowInsecure
+	hckioClient, err := hckioClients.Get(apiKey, controllers.ClientOptions{})
+	if err != nil {
+		setupLog.Error(err, "unable to create healthchecks.io client")
+		os.Exit(1)
+	}
+	hckio := controllers.NewHealthchecksioAPI(hckioClient)
+
+	checkCollector := controllers.NewCheckCollector(mgr.GetClient(), ctrl.Log.WithName("metrics"))
+	if err = metrics.Registry.Register(checkCollector); err != nil {
+		setupLog.Error(err, "unable to register check metrics")
+		os.Exit(1)
+	}
+	if err = mgr.Add(checkCollector); err != nil {
+		setupLog.Error(err, "unable to add check metrics")
+		os.Exit(1)
+	}
+
+	poller := controllers.NewStatusPoller(ctrl.Log.WithName("status-poller"), reconcileInterval)
+	if err = mgr.Add(poller); err != nil {
+		setupLog.Error(err, "unable to add status poller")
+		os.Exit(1)
+	}
+
+	var notifications chan event.GenericEvent
+	if notificationAddr != "" {
+		notifications = make(chan event.GenericEvent, 100)
+		if err = mgr.Add(&controllers.NotificationReceiver{
+			Client: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("notification-receiver"),
+			Addr:   notificationAddr,
+			Secret: notificationSecret,
+			Events: notifications,
+			Poller: poller,
+		}); err != nil {
+			setupLog.Error(err, "unable to add notification receiver")
+			os.Exit(1)
+		}
+
+		// the notification Service selects the pod of the leader by its label
+		if name, namespace := os.Getenv("POD_NAME"), os.Getenv("POD_NAMESPACE"); name != "" && namespace != "" {
+			labeler := &controllers.LeaderLabeler{
+				Client:    mgr.GetClient(),
+				Log:       ctrl.Log.WithName("leader-labeler"),
+				Name:      name,
+				Namespace: namespace,
+			}
+			if err = labeler.Clear(context.Background()); err != nil {
+				setupLog.Error(err, "unable to remove the leader label from the pod of the operator")
+				os.Exit(1)
+			}
+			if err = mgr.Add(labeler); err != nil {
+				setupLog.Error(err, "unable to add leader labeler")
+				os.Exit(1)
+			}
+		} else {
+			setupLog.Info("POD_NAME and POD_NAMESPACE are not set, the pod of the leader is not labeled for the notification Service")
+		}
 	}
 
 	if err = (&controllers.CheckReconciler{
 		Client:            mgr.GetClient(),
 		Scheme:            mgr.GetScheme(),
 		Log:               ctrl.Log.WithName("controllers").WithName("Check"),
-		Hckio:             hckioClient,
+		Recorder:          mgr.GetEventRecorderFor("check-controller"),
+		Hckio:             hckio,
+		Clients:           hckioClients,
 		Clock:             controllers.NewClock(),
 		ReconcileInterval: reconcileInterval,
 		NamePrefix:        namePrefix,
+		OwnershipTag:      ownershipTag,
+		DeletionPolicy:    deletionPolicy,
+		Notifications:     notifications,
+		Poller:            poller,
+		Limiter:           apiLimiter,
+		APIReader:         mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Check")
 		os.Exit(1)
 	}
+	if err = (&controllers.HealthchecksProjectReconciler{
+		Client:            mgr.GetClient(),
+		Log:               ctrl.Log.WithName("controllers").WithName("HealthchecksProject"),
+		Clients:           hckioClients,
+		Clock:             controllers.NewClock(),
+		ReconcileInterval: reconcileInterval,
+		APIReader:         mgr.GetAPIReader(),
+		Poller:            poller,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "HealthchecksProject")
+		os.Exit(1)
+	}
+	if err = (&controllers.ClusterHealthchecksProjectReconciler{
+		Client:            mgr.GetClient(),
+		Log:               ctrl.Log.WithName("controllers").WithName("ClusterHealthchecksProject"),
+		Clients:           hckioClients,
+		Clock:             controllers.NewClock(),
+		ReconcileInterval: reconcileInterval,
+		APIReader:         mgr.GetAPIReader(),
+		Poller:            poller,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterHealthchecksProject")
+		os.Exit(1)
+	}
+	if err = (&controllers.CronJobReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Log:      ctrl.Log.WithName("controllers").WithName("CronJob"),
+		Recorder: mgr.GetEventRecorderFor("cronjob-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CronJob")
+		os.Exit(1)
+	}
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create kubernetes clientset")
+		os.Exit(1)
+	}
+	if err = (&controllers.JobReconciler{
+		Client:    mgr.GetClient(),
+		Log:       ctrl.Log.WithName("controllers").WithName("Job"),
+		Recorder:  mgr.GetEventRecorderFor("job-controller"),
+		Pinger:    controllers.NewPinger(httpClient),
+		PodLogs:   controllers.NewPodLogReader(clientset),
+		APIReader: mgr.GetAPIReader(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Job")
+		os.Exit(1)
+	}
+	if err = (&controllers.ProbeReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("Probe"),
+		Recorder: mgr.GetEventRecorderFor("probe-controller"),
+		Pinger:   controllers.NewPinger(httpClient),
+		Prober:   prober,
+		Clock:    controllers.NewClock(),
+
+		MaxConcurrentReconciles: probeWorkers,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Probe")
+		os.Exit(1)
+	}
+	selfCheckHealthcheckName := ""
+	if selfCheckName != "" {
+		selfCheck := &controllers.SelfCheck{
+			Client:       mgr.GetClient(),
+			Log:          ctrl.Log.WithName("self-check"),
+			Hckio:        hckio,
+			Pinger:       controllers.NewPinger(httpClient),
+			Interval:     reconcileInterval,
+			Name:         selfCheckName,
+			Schedule:     selfCheckSchedule,
+			Channels:     controllers.SplitList(selfCheckChannels),
+			NamePrefix:   namePrefix,
+			OwnershipTag: ownershipTag,
+		}
+		if err = mgr.Add(selfCheck); err != nil {
+			setupLog.Error(err, "unable to add self check")
+			os.Exit(1)
+		}
+		selfCheckHealthcheckName = selfCheck.HealthcheckName()
+	}
+	if gcInterval > 0 {
+		if err = mgr.Add(&controllers.GarbageCollector{
+			Client:        mgr.GetClient(),
+			Log:           ctrl.Log.WithName("garbage-collector"),
+			Recorder:      mgr.GetEventRecorderFor("garbage-collector"),
+			Hckio:         hckio,
+			Clients:       hckioClients,
+			APIReader:     mgr.GetAPIReader(),
+			EventObject:   operatorPod(),
+			Clock:         controllers.NewClock(),
+			Interval:      gcInterval,
+			GracePeriod:   gcGracePeriod,
+			Delete:        gcDelete,
+			DryRun:        gcDryRun,
+			NamePrefix:    namePrefix,
+			OwnershipTag:  ownershipTag,
+			SelfCheckName: selfCheckHealthcheckName,
+		}); err != nil {
+			setupLog.Error(err, "unable to add garbage collector")
+			os.Exit(1)
+		}
+	}
+	if enableWebhooks {
+		if err = (&monitoringv1alpha1.Check{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Check")
+			os.Exit(1)
+		}
+		mgr.GetWebhookServer().Register(webhooks.PodPath, &webhook.Admission{
+			Handler: &webhooks.PodPingURLInjector{Client: mgr.GetClient()},
+		})
+		mgr.GetWebhookServer().Register(webhooks.JobPath, &webhook.Admission{
+			Handler: &webhooks.JobPingURLInjector{Client: mgr.GetClient()},
+		})
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -130,6 +465,61 @@ func main() {
 	}
 }
 
+// newHTTPClient creates the http client used to call the healthchecks.io API and send pings,
+// trusting the CA bundle read from caBundlePath
+func newHTTPClient(caBundlePath string, insecureSkipVerify bool, timeout time.Duration) (*http.Client, error) {
+	if caBundlePath == "" && !insecureSkipVerify {
+		return &http.Client{Timeout: timeout}, nil
+	}
+
+	var caBundle []byte
+	if caBundlePath != "" {
+		data, err := ioutil.ReadFile(caBundlePath)
+		if err != nil {
+			return nil, err
+		}
+		caBundle = data
+	}
+
+	client, err := controllers.NewHTTPClient(caBundle, insecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = timeout
+	return client, nil
+}
+
+// newProber creates the prober running the probes of checks, connecting to public addresses and allowedNetworks.
+// The API server is blocked along with the default blocked networks, even when in allowedNetworks.
+func newProber(allowedNetworks string) (*controllers.Prober, error) {
+	prober := controllers.NewProber()
+
+	allowed, err := controllers.ParseNetworks(controllers.SplitList(allowedNetworks))
+	if err != nil {
+		return nil, fmt.Errorf("probe-allowed-networks: %s", err)
+	}
+	prober.AllowedNetworks = allowed
+
+	if ip := net.ParseIP(os.Getenv("KUBERNETES_SERVICE_HOST")); ip != nil {
+		bits := 128
+		if v4 := ip.To4(); v4 != nil {
+			ip, bits = v4, 32
+		}
+		prober.BlockedNetworks = append(prober.BlockedNetworks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+
+	return prober, nil
+}
+
+// operatorPod returns a reference to the pod of the operator, when its name and namespace are set through the downward API
+func operatorPod() runtime.Object {
+	name, namespace := os.Getenv("POD_NAME"), os.Getenv("POD_NAMESPACE")
+	if name == "" || namespace == "" {
+		return nil
+	}
+	return &corev1.ObjectReference{APIVersion: "v1", Kind: "Pod", Name: name, Namespace: namespace}
+}
+
 func envOrDefaultString(key, defaultValue string) string {
 	v := os.Getenv(key)
 	if v != "" {
@@ -156,6 +546,24 @@ func envOrDefaultDuration(key string, defaultValue time.Duration) time.Duration
 	return pv
 }
 
+func envOrDefaultInt(key string, defaultValue int) int {
+	v := envOrDefaultString(key, strconv.Itoa(defaultValue))
+	pv, err := strconv.Atoi(v)
+	if err != nil {
+		log.Panicf("failed parsing integer from environment variable %s", key)
+	}
+	return pv
+}
+
+func envOrDefaultFloat(key string, defaultValue float64) float64 {
+	v := envOrDefaultString(key, strconv.FormatFloat(defaultValue, 'f', -1, 64))
+	pv, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Panicf("failed parsing float from environment variable %s", key)
+	}
+	return pv
+}
+
 type logrLogger struct {
 	log logr.Logger
 }
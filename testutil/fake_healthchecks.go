@@ -0,0 +1,584 @@
+package testutil
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+)
+
+// Defaults of a check created without a timeout or grace period, as in healthchecks.io
+const (
+	FakeDefaultTimeout = 86400
+	FakeDefaultGrace   = 3600
+)
+
+// FakeHealthchecks is a stateful in-memory fake of the healthchecks.io management API, served at the
+// root of its URL, and of its ping endpoints, served under /ping/. Point a client at it with
+// NewTestHealthchecksioClient(t, apiKey, fake.URL).
+type FakeHealthchecks struct {
+	*httptest.Server
+
+	// APIKey is the API key requests to the management API must be sent with, any key is accepted when empty
+	APIKey string
+
+	// Now returns the current time, used for the time of pings and flips
+	Now func() time.Time
+
+	mu       sync.Mutex
+	checks   map[string]*FakeCheck
+	channels []healthchecksio.HealthcheckChannelResponse
+	requests []FakeRequest
+	failures []int
+}
+
+// FakeCheck is a check held by FakeHealthchecks
+type FakeCheck struct {
+	ID       string
+	Name     string
+	Tags     string
+	Timeout  int
+	Grace    int
+	Schedule string
+	Timezone string
+	Channels string
+	Status   string
+	Pings    []FakePing
+	Flips    []FakeFlip
+}
+
+// FakePing is a ping received by a FakeCheck
+type FakePing struct {
+	Type   string    `json:"type"`
+	Date   time.Time `json:"date"`
+	N      int       `json:"n"`
+	Scheme string    `json:"scheme"`
+	Method string    `json:"method"`
+	UA     string    `json:"ua"`
+	Body   string    `json:"-"`
+}
+
+// FakeFlip is a change of the status of a FakeCheck between up and down
+type FakeFlip struct {
+	Timestamp time.Time `json:"timestamp"`
+	Up        int       `json:"up"`
+}
+
+// FakeRequest is a request received by FakeHealthchecks
+type FakeRequest struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// String returns the method and path of the request, e.g. "POST /checks/"
+func (r FakeRequest) String() string {
+	return r.Method + " " + r.Path
+}
+
+// fakeCheckRequest is the body of a create or update request, fields left out are not changed
+type fakeCheckRequest struct {
+	Name     *string  `json:"name"`
+	Tags     *string  `json:"tags"`
+	Timeout  *int     `json:"timeout"`
+	Grace    *int     `json:"grace"`
+	Schedule *string  `json:"schedule"`
+	Timezone *string  `json:"tz"`
+	Channels *string  `json:"channels"`
+	Unique   []string `json:"unique"`
+}
+
+// NewFakeHealthchecks starts a new FakeHealthchecks, close it when done
+func NewFakeHealthchecks() *FakeHealthchecks {
+	f := &FakeHealthchecks{
+		Now:      time.Now,
+		checks:   make(map[string]*FakeCheck),
+		channels: make([]healthchecksio.HealthcheckChannelResponse, 0),
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// AddChannel adds a notification channel and returns its id
+func (f *FakeHealthchecks) AddChannel(name, kind string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := newFakeUUID()
+	f.channels = append(f.channels, healthchecksio.HealthcheckChannelResponse{ID: id, Name: name, Kind: kind})
+	return id
+}
+
+// AddCheck adds a check, as if it was created outside of the test, and returns its id
+func (f *FakeHealthchecks) AddCheck(check FakeCheck) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if check.ID == "" {
+		check.ID = newFakeUUID()
+	}
+	if check.Status == "" {
+		check.Status = "new"
+	}
+	f.checks[check.ID] = &check
+	return check.ID
+}
+
+// Check returns a copy of the check with the id
+func (f *FakeHealthchecks) Check(id string) (FakeCheck, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	check, ok := f.checks[id]
+	if !ok {
+		return FakeCheck{}, false
+	}
+	return *check, true
+}
+
+// CheckByName returns a copy of the check with the name
+func (f *FakeHealthchecks) CheckByName(name string) (FakeCheck, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, check := range f.checks {
+		if check.Name == name {
+			return *check, true
+		}
+	}
+	return FakeCheck{}, false
+}
+
+// Checks returns copies of all checks, sorted by name
+func (f *FakeHealthchecks) Checks() []FakeCheck {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checks := make([]FakeCheck, 0, len(f.checks))
+	for _, check := range f.checks {
+		checks = append(checks, *check)
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].Name < checks[j].Name })
+	return checks
+}
+
+// FailRequests makes the next count requests to the management API fail with the status code
+func (f *FakeHealthchecks) FailRequests(statusCode, count int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := 0; i < count; i++ {
+		f.failures = append(f.failures, statusCode)
+	}
+}
+
+// Requests returns the requests received so far
+func (f *FakeHealthchecks) Requests() []FakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeRequest{}, f.requests...)
+}
+
+// ResetRequests forgets the requests received so far
+func (f *FakeHealthchecks) ResetRequests() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = nil
+}
+
+// AssertRequests fails the test unless the requests received so far, formatted as "METHOD /path", are the expected ones
+func (f *FakeHealthchecks) AssertRequests(t testing.TB, expected ...string) {
+	t.Helper()
+
+	actual := make([]string, 0)
+	for _, r := range f.Requests() {
+		actual = append(actual, r.String())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected requests\n\t%s\nbut got\n\t%s", strings.Join(expected, "\n\t"), strings.Join(actual, "\n\t"))
+	}
+}
+
+// AssertCheck fails the test unless a check with the name exists, returning it
+func (f *FakeHealthchecks) AssertCheck(t testing.TB, name string) FakeCheck {
+	t.Helper()
+
+	check, ok := f.CheckByName(name)
+	if !ok {
+		t.Errorf("expected a check named %s", name)
+	}
+	return check
+}
+
+// PingURL returns the ping URL of the check with the id
+func (f *FakeHealthchecks) PingURL(id string) string {
+	return f.URL + "/ping/" + id
+}
+
+func (f *FakeHealthchecks) serveHTTP(res http.ResponseWriter, req *http.Request) {
+	b, _ := ioutil.ReadAll(req.Body)
+	body := string(b)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, FakeRequest{Method: req.Method, Path: req.URL.Path, Body: body})
+
+	if strings.HasPrefix(req.URL.Path, "/ping/") {
+		f.ping(res, req, body)
+		return
+	}
+
+	if f.APIKey != "" && req.Header.Get("X-Api-Key") != f.APIKey {
+		writeFakeJSON(res, http.StatusUnauthorized, map[string]string{"error": "wrong api key"})
+		return
+	}
+
+	if len(f.failures) > 0 {
+		status := f.failures[0]
+		f.failures = f.failures[1:]
+		writeFakeJSON(res, status, map[string]string{"error": http.StatusText(status)})
+		return
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "channels" && req.Method == http.MethodGet:
+		writeFakeJSON(res, http.StatusOK, map[string]interface{}{"channels": f.channels})
+	case len(parts) == 1 && parts[0] == "checks" && req.Method == http.MethodGet:
+		f.list(res, req)
+	case len(parts) == 1 && parts[0] == "checks" && req.Method == http.MethodPost:
+		f.create(res, body)
+	case len(parts) >= 2 && parts[0] == "checks":
+		check, ok := f.checks[parts[1]]
+		if !ok {
+			writeFakeJSON(res, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		f.serveCheck(res, req, check, parts[2:], body)
+	default:
+		writeFakeJSON(res, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+func (f *FakeHealthchecks) serveCheck(res http.ResponseWriter, req *http.Request, check *FakeCheck, sub []string, body string) {
+	action := strings.Join(sub, "/")
+	switch {
+	case action == "" && req.Method == http.MethodGet:
+		writeFakeJSON(res, http.StatusOK, f.response(check))
+	case action == "" && req.Method == http.MethodPost:
+		var update fakeCheckRequest
+		if err := json.Unmarshal([]byte(body), &update); err != nil {
+			writeFakeJSON(res, http.StatusBadRequest, map[string]string{"error": "could not parse request body"})
+			return
+		}
+		if err := f.apply(check, update); err != nil {
+			writeFakeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeFakeJSON(res, http.StatusOK, f.response(check))
+	case action == "" && req.Method == http.MethodDelete:
+		delete(f.checks, check.ID)
+		writeFakeJSON(res, http.StatusOK, f.response(check))
+	case action == "pause" && req.Method == http.MethodPost:
+		check.Status = "paused"
+		writeFakeJSON(res, http.StatusOK, f.response(check))
+	case action == "pings" && req.Method == http.MethodGet:
+		pings := make([]FakePing, 0, len(check.Pings))
+		for i := len(check.Pings) - 1; i >= 0; i-- {
+			pings = append(pings, check.Pings[i])
+		}
+		writeFakeJSON(res, http.StatusOK, map[string]interface{}{"pings": pings})
+	case action == "flips" && req.Method == http.MethodGet:
+		flips := make([]FakeFlip, 0, len(check.Flips))
+		for i := len(check.Flips) - 1; i >= 0; i-- {
+			flips = append(flips, check.Flips[i])
+		}
+		writeFakeJSON(res, http.StatusOK, flips)
+	default:
+		writeFakeJSON(res, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (f *FakeHealthchecks) list(res http.ResponseWriter, req *http.Request) {
+	tags := req.URL.Query()["tag"]
+
+	ids := make([]string, 0, len(f.checks))
+	for id, check := range f.checks {
+		if hasFakeTags(check.Tags, tags) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	checks := make([]healthchecksio.HealthcheckResponse, 0, len(ids))
+	for _, id := range ids {
+		checks = append(checks, f.response(f.checks[id]))
+	}
+	writeFakeJSON(res, http.StatusOK, map[string]interface{}{"checks": checks})
+}
+
+// create creates a check, or updates the existing check matching the unique fields of the request
+func (f *FakeHealthchecks) create(res http.ResponseWriter, body string) {
+	var create fakeCheckRequest
+	if err := json.Unmarshal([]byte(body), &create); err != nil {
+		writeFakeJSON(res, http.StatusBadRequest, map[string]string{"error": "could not parse request body"})
+		return
+	}
+
+	check := &FakeCheck{Timeout: FakeDefaultTimeout, Grace: FakeDefaultGrace, Status: "new"}
+	if err := f.apply(check, create); err != nil {
+		writeFakeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if len(create.Unique) > 0 {
+		for _, id := range f.sortedIDs() {
+			existing := f.checks[id]
+			if matchesFakeUnique(existing, check, create.Unique) {
+				if err := f.apply(existing, create); err != nil {
+					writeFakeJSON(res, http.StatusBadRequest, map[string]string{"error": err.Error()})
+					return
+				}
+				writeFakeJSON(res, http.StatusOK, f.response(existing))
+				return
+			}
+		}
+	}
+
+	check.ID = newFakeUUID()
+	f.checks[check.ID] = check
+	writeFakeJSON(res, http.StatusCreated, f.response(check))
+}
+
+// apply sets the fields of the request on the check
+func (f *FakeHealthchecks) apply(check *FakeCheck, req fakeCheckRequest) error {
+	for _, field := range req.Unique {
+		if field != "name" && field != "tags" && field != "timeout" && field != "grace" {
+			return fmt.Errorf("unique accepts only name, tags, timeout and grace")
+		}
+	}
+
+	if req.Channels != nil {
+		channels, err := f.resolveChannels(*req.Channels)
+		if err != nil {
+			return err
+		}
+		check.Channels = channels
+	}
+	if req.Name != nil {
+		check.Name = *req.Name
+	}
+	if req.Tags != nil {
+		check.Tags = strings.TrimSpace(*req.Tags)
+	}
+	if req.Timeout != nil {
+		check.Timeout = *req.Timeout
+	}
+	if req.Grace != nil {
+		check.Grace = *req.Grace
+	}
+	if req.Schedule != nil {
+		check.Schedule = *req.Schedule
+	}
+	if req.Timezone != nil {
+		check.Timezone = *req.Timezone
+	}
+
+	return nil
+}
+
+// resolveChannels returns the ids of the channels, where * stands for all channels
+func (f *FakeHealthchecks) resolveChannels(value string) (string, error) {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(value, ",") {
+		id = strings.TrimSpace(id)
+		switch id {
+		case "":
+			continue
+		case "*":
+			for _, c := range f.channels {
+				ids = append(ids, c.ID)
+			}
+			continue
+		}
+
+		found := false
+		for _, c := range f.channels {
+			if c.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("invalid channel identifier: %s", id)
+		}
+		ids = append(ids, id)
+	}
+
+	return strings.Join(ids, ","), nil
+}
+
+// ping records a ping and updates the status of the check. Supported are /ping/<id>, /ping/<id>/start,
+// /ping/<id>/fail and /ping/<id>/<exit status>.
+func (f *FakeHealthchecks) ping(res http.ResponseWriter, req *http.Request, body string) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, "/ping/"), "/"), "/")
+	check, ok := f.checks[parts[0]]
+	if !ok || len(parts) > 2 {
+		http.Error(res, "not found", http.StatusNotFound)
+		return
+	}
+
+	kind := "success"
+	if len(parts) == 2 {
+		switch parts[1] {
+		case "start":
+			kind = "start"
+		case "fail":
+			kind = "fail"
+		default:
+			code, err := strconv.Atoi(parts[1])
+			if err != nil || code < 0 || code > 255 {
+				http.Error(res, "not found", http.StatusNotFound)
+				return
+			}
+			if code != 0 {
+				kind = "fail"
+			}
+		}
+	}
+
+	now := f.Now()
+	check.Pings = append(check.Pings, FakePing{
+		Type:   kind,
+		Date:   now,
+		N:      len(check.Pings) + 1,
+		Scheme: "http",
+		Method: req.Method,
+		UA:     req.UserAgent(),
+		Body:   body,
+	})
+
+	status := check.Status
+	switch kind {
+	case "success":
+		status = "up"
+	case "fail":
+		status = "down"
+	}
+	if status != check.Status && (status == "down" || status == "up") {
+		up := 0
+		if status == "up" {
+			up = 1
+		}
+		check.Flips = append(check.Flips, FakeFlip{Timestamp: now, Up: up})
+	}
+	check.Status = status
+
+	res.Write([]byte("OK"))
+}
+
+// response returns the check as returned by the API
+func (f *FakeHealthchecks) response(check *FakeCheck) healthchecksio.HealthcheckResponse {
+	res := healthchecksio.HealthcheckResponse{
+		Name:      check.Name,
+		Tags:      check.Tags,
+		Grace:     check.Grace,
+		Pings:     len(check.Pings),
+		Status:    check.Status,
+		Channels:  check.Channels,
+		PingURL:   f.PingURL(check.ID),
+		UpdateURL: f.URL + "/checks/" + check.ID,
+		PauseURL:  f.URL + "/checks/" + check.ID + "/pause",
+	}
+	if check.Schedule != "" {
+		res.Schedule = check.Schedule
+		res.Timezone = check.Timezone
+	} else {
+		res.Timeout = check.Timeout
+	}
+	for i := len(check.Pings) - 1; i >= 0; i-- {
+		if check.Pings[i].Type != "start" {
+			res.LastPing = check.Pings[i].Date.UTC().Format(time.RFC3339)
+			break
+		}
+	}
+	return res
+}
+
+func (f *FakeHealthchecks) sortedIDs() []string {
+	ids := make([]string, 0, len(f.checks))
+	for id := range f.checks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func matchesFakeUnique(existing, check *FakeCheck, unique []string) bool {
+	for _, field := range unique {
+		switch field {
+		case "name":
+			if existing.Name != check.Name {
+				return false
+			}
+		case "tags":
+			if existing.Tags != check.Tags {
+				return false
+			}
+		case "timeout":
+			if existing.Timeout != check.Timeout {
+				return false
+			}
+		case "grace":
+			if existing.Grace != check.Grace {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func hasFakeTags(checkTags string, tags []string) bool {
+	have := strings.Fields(checkTags)
+	for _, tag := range tags {
+		found := false
+		for _, t := range have {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func writeFakeJSON(res http.ResponseWriter, status int, v interface{}) {
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(v)
+}
+
+func newFakeUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,108 @@
+package testutil
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	healthchecksio "github.com/kristofferahl/go-healthchecksio"
+)
+
+func TestFakeHealthchecks_UniqueUpsert(t *testing.T) {
+	g := NewGomegaWithT(t)
+	fake := NewFakeHealthchecks()
+	defer fake.Close()
+	fake.APIKey = "api-key"
+	email := fake.AddChannel("Ops", "email")
+	client := NewTestHealthchecksioClient(t, "api-key", fake.URL)
+
+	created, err := client.Create(healthchecksio.Healthcheck{Name: "default/foo", Timeout: 60, Channels: "*", Unique: []string{"name"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(created.Status).To(Equal("new"))
+	g.Expect(created.Grace).To(Equal(FakeDefaultGrace))
+	g.Expect(created.Channels).To(Equal(email))
+	g.Expect(created.PingURL).To(Equal(fake.PingURL(created.ID())))
+
+	updated, err := client.Create(healthchecksio.Healthcheck{Name: "default/foo", Timeout: 120, Unique: []string{"name"}})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(updated.ID()).To(Equal(created.ID()))
+	g.Expect(updated.Timeout).To(Equal(120))
+	g.Expect(updated.Channels).To(Equal(email), "fields left out are not changed")
+
+	other, err := client.Create(healthchecksio.Healthcheck{Name: "default/foo"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(other.ID()).ToNot(Equal(created.ID()), "a check without unique fields is always created")
+
+	_, err = client.Create(healthchecksio.Healthcheck{Name: "default/bar", Channels: "missing"})
+	g.Expect(err).To(MatchError(ContainSubstring("invalid channel identifier")))
+
+	_, err = NewTestHealthchecksioClient(t, "wrong-key", fake.URL).GetAll()
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(fake.Checks()).To(HaveLen(2))
+	fake.AssertRequests(t, "POST /checks/", "POST /checks/", "POST /checks/", "POST /checks/", "GET /checks/")
+}
+
+func TestFakeHealthchecks_UpdatePauseDelete(t *testing.T) {
+	g := NewGomegaWithT(t)
+	fake := NewFakeHealthchecks()
+	defer fake.Close()
+	id := fake.AddCheck(FakeCheck{Name: "default/foo", Tags: "prod", Timeout: 60})
+	fake.AddCheck(FakeCheck{Name: "default/bar"})
+	client := NewTestHealthchecksioClient(t, "api-key", fake.URL)
+
+	checks, err := client.GetAll()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(checks).To(HaveLen(2))
+
+	updated, err := client.Update(id, healthchecksio.Healthcheck{Schedule: "0 * * * *", Timezone: "Europe/Stockholm"})
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(updated.Schedule).To(Equal("0 * * * *"))
+	g.Expect(updated.Tags).To(Equal("prod"))
+
+	paused, err := client.Pause(id)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(paused.Status).To(Equal("paused"))
+
+	_, err = client.Delete(id)
+	g.Expect(err).ToNot(HaveOccurred())
+	_, ok := fake.Check(id)
+	g.Expect(ok).To(BeFalse())
+
+	_, err = client.Delete(id)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.(*healthchecksio.APIError).StatusCode()).To(Equal(http.StatusNotFound))
+}
+
+func TestFakeHealthchecks_Pings(t *testing.T) {
+	g := NewGomegaWithT(t)
+	fake := NewFakeHealthchecks()
+	defer fake.Close()
+	now := time.Date(2019, 11, 10, 12, 0, 0, 0, time.UTC)
+	fake.Now = func() time.Time { return now }
+	id := fake.AddCheck(FakeCheck{Name: "default/foo"})
+
+	for _, path := range []string{"", "/start", "/fail", "/0", "/1"} {
+		res, err := http.Post(fake.PingURL(id)+path, "text/plain", strings.NewReader("output"))
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(res.StatusCode).To(Equal(http.StatusOK))
+	}
+	res, err := http.Post(fake.PingURL(id)+"/unknown", "text/plain", nil)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+
+	check, _ := fake.Check(id)
+	g.Expect(check.Status).To(Equal("down"))
+	g.Expect(check.Pings).To(HaveLen(5))
+	g.Expect(check.Pings[2].Type).To(Equal("fail"))
+	g.Expect(check.Pings[2].Body).To(Equal("output"))
+	g.Expect(check.Flips).To(Equal([]FakeFlip{{now, 1}, {now, 0}, {now, 1}, {now, 0}}))
+
+	hc, err := NewTestHealthchecksioClient(t, "api-key", fake.URL).GetAll()
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(hc[0].Pings).To(Equal(5))
+	g.Expect(hc[0].LastPing).To(Equal("2019-11-10T12:00:00Z"))
+}
@@ -0,0 +1,65 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"net/http"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// JobPath is the path the Job webhook is served on
+const JobPath = "/mutate-batch-v1-job"
+
+// The webhook only receives the Jobs labeled with LabelInjectPingURLs, see config/webhook/pod_webhook_patch.yaml,
+// and fails closed so Jobs referencing a missing Check are rejected even when the operator is unavailable.
+// +kubebuilder:webhook:path=/mutate-batch-v1-job,mutating=true,failurePolicy=fail,groups=batch,resources=jobs,verbs=create,versions=v1,name=mjob.monitoring.healthchecks.io
+
+// JobPingURLInjector injects the ping URLs of a Check as environment variables into the pod template of annotated Jobs,
+// so their pods get them without being labeled and annotated themselves. Jobs of a CronJob get the labels and
+// annotations of its job template.
+type JobPingURLInjector struct {
+	Client  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &JobPingURLInjector{}
+var _ admission.DecoderInjector = &JobPingURLInjector{}
+
+// Handle injects the ping URLs, denying Jobs referencing a Check that does not exist or has no ping URL yet
+func (i *JobPingURLInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	job := &batchv1.Job{}
+	if err := i.decoder.Decode(req, job); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	urls, denied := checkPingURLs(ctx, i.Client, req.Namespace, job.Annotations)
+	if denied != nil {
+		return *denied
+	}
+
+	injectPingURLs(&job.Spec.Template.Spec, urls)
+	return patchResponse(req, job)
+}
+
+// InjectDecoder injects the decoder
+func (i *JobPingURLInjector) InjectDecoder(d *admission.Decoder) error {
+	i.decoder = d
+	return nil
+}
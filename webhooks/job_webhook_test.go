@@ -0,0 +1,78 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+func TestJobWebhook_Handle(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ready := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "default"},
+		Status: monitoringv1alpha1.CheckStatus{
+			ID:      "e71024f4-8537-4dd2-b742-ebe5a1685776",
+			PingURL: "https://hc-ping.com/e71024f4-8537-4dd2-b742-ebe5a1685776",
+		},
+	}
+	pod := newPodPingURLInjector(t, ready)
+	injector := &JobPingURLInjector{Client: pod.Client}
+	injector.InjectDecoder(pod.decoder)
+
+	// Jobs without the annotation are allowed as is
+	res := injector.Handle(context.TODO(), newJobRequest(t, nil))
+	g.Expect(res.Allowed).To(BeTrue())
+	g.Expect(res.Patches).To(BeEmpty())
+
+	// Jobs referencing a Check with a ping url get the ping urls in their pod template
+	res = injector.Handle(context.TODO(), newJobRequest(t, map[string]string{AnnotationCheck: "ready"}))
+	g.Expect(res.Allowed).To(BeTrue())
+	g.Expect(res.Patches).To(HaveLen(1))
+	g.Expect(res.Patches[0].Path).To(Equal("/spec/template/spec/containers/0/env"))
+	g.Expect(res.Patches[0].Value).To(ContainElement(HaveKeyWithValue("value", "https://hc-ping.com/e71024f4-8537-4dd2-b742-ebe5a1685776/start")))
+
+	// Jobs referencing a Check that does not exist are denied
+	res = injector.Handle(context.TODO(), newJobRequest(t, map[string]string{AnnotationCheck: "missing"}))
+	g.Expect(res.Allowed).To(BeFalse())
+}
+
+func newJobRequest(t *testing.T, annotations map[string]string) admission.Request {
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "backup",
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers:    []corev1.Container{{Name: "main", Image: "backup"}},
+					RestartPolicy: corev1.RestartPolicyNever,
+				},
+			},
+		},
+	}
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Namespace: "default",
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}
@@ -0,0 +1,154 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrs "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+	"github.com/kristofferahl/healthchecksio-operator/controllers"
+)
+
+// LabelInjectPingURLs opts a pod or Job into the pod or Job webhook, only objects labeled "true" are sent to the webhooks
+const LabelInjectPingURLs = "healthchecks.io/inject-ping-urls"
+
+// Annotations read from a pod, usually set through the pod template of a Job or CronJob, or from a Job
+const (
+	// AnnotationCheck is the name of the Check, in the namespace of the pod, whose ping URLs are injected
+	AnnotationCheck = "healthchecks.io/check"
+
+	// AnnotationPingURLEnv is the name of the environment variable holding the ping URL, defaults to HC_PING_URL.
+	// The start, fail and log URLs are injected in the same variable suffixed with _START, _FAIL and _LOG.
+	AnnotationPingURLEnv = "healthchecks.io/ping-url-env"
+)
+
+// PodPath is the path the pod webhook is served on
+const PodPath = "/mutate-v1-pod"
+
+// The webhook only receives the pods labeled with LabelInjectPingURLs, see config/webhook/pod_webhook_patch.yaml,
+// and fails closed so pods referencing a missing Check are rejected even when the operator is unavailable.
+// +kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=fail,groups="",resources=pods,verbs=create,versions=v1,name=mpod.monitoring.healthchecks.io
+
+// PodPingURLInjector injects the ping URLs of a Check as environment variables into the containers of annotated pods
+type PodPingURLInjector struct {
+	Client  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &PodPingURLInjector{}
+var _ admission.DecoderInjector = &PodPingURLInjector{}
+
+// Handle injects the ping URLs, denying pods referencing a Check that does not exist or has no ping URL yet
+func (i *PodPingURLInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := i.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	urls, denied := checkPingURLs(ctx, i.Client, req.Namespace, pod.Annotations)
+	if denied != nil {
+		return *denied
+	}
+
+	injectPingURLs(&pod.Spec, urls)
+	return patchResponse(req, pod)
+}
+
+// InjectDecoder injects the decoder
+func (i *PodPingURLInjector) InjectDecoder(d *admission.Decoder) error {
+	i.decoder = d
+	return nil
+}
+
+// checkPingURLs returns the ping URLs of the Check referenced by annotations, in namespace, or the response
+// of the webhook when no Check is referenced or the referenced Check does not exist or has no ping URL yet
+func checkPingURLs(ctx context.Context, c client.Reader, namespace string, annotations map[string]string) (map[string]string, *admission.Response) {
+	name, ok := annotations[AnnotationCheck]
+	if !ok {
+		res := admission.Allowed("no check referenced")
+		return nil, &res
+	}
+
+	// the namespace of an object is not always set on create, the namespace of the request is
+	var check monitoringv1alpha1.Check
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, &check); err != nil {
+		res := admission.Errored(http.StatusInternalServerError, err)
+		if apierrs.IsNotFound(err) {
+			res = admission.Denied(fmt.Sprintf("check %s referenced by annotation %s not found", name, AnnotationCheck))
+		}
+		return nil, &res
+	}
+
+	if check.Status.ID == "" || check.Status.PingURL == "" {
+		res := admission.Denied(fmt.Sprintf("check %s referenced by annotation %s has no ping url yet", name, AnnotationCheck))
+		return nil, &res
+	}
+
+	return controllers.PingURLs(check.Status.PingURL, annotations[AnnotationPingURLEnv]), nil
+}
+
+// patchResponse returns the response patching the object of the request into obj
+func patchResponse(req admission.Request, obj interface{}) admission.Response {
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// injectPingURLs adds the ping URLs as environment variables to every container of the pod spec,
+// keeping the variables already set on a container
+func injectPingURLs(spec *corev1.PodSpec, urls map[string]string) {
+	names := make([]string, 0, len(urls))
+	for name := range urls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	inject := func(containers []corev1.Container) {
+		for i := range containers {
+			c := &containers[i]
+			for _, name := range names {
+				if !hasEnv(*c, name) {
+					c.Env = append(c.Env, corev1.EnvVar{Name: name, Value: urls[name]})
+				}
+			}
+		}
+	}
+
+	inject(spec.InitContainers)
+	inject(spec.Containers)
+}
+
+func hasEnv(c corev1.Container, name string) bool {
+	for _, e := range c.Env {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
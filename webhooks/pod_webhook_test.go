@@ -0,0 +1,116 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	. "github.com/onsi/gomega"
+
+	monitoringv1alpha1 "github.com/kristofferahl/healthchecksio-operator/api/v1alpha1"
+)
+
+func TestPodWebhook_InjectPingURLs(t *testing.T) {
+	g := NewGomegaWithT(t)
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init"}},
+			Containers: []corev1.Container{
+				{Name: "main", Env: []corev1.EnvVar{{Name: "HC_PING_URL", Value: "custom"}}},
+			},
+		},
+	}
+
+	injectPingURLs(&pod.Spec, map[string]string{"HC_PING_URL": "url", "HC_PING_URL_FAIL": "url/fail"})
+
+	g.Expect(pod.Spec.InitContainers[0].Env).To(Equal([]corev1.EnvVar{
+		{Name: "HC_PING_URL", Value: "url"},
+		{Name: "HC_PING_URL_FAIL", Value: "url/fail"},
+	}))
+	g.Expect(pod.Spec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+		{Name: "HC_PING_URL", Value: "custom"},
+		{Name: "HC_PING_URL_FAIL", Value: "url/fail"},
+	}))
+}
+
+func TestPodWebhook_Handle(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ready := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "ready", Namespace: "default"},
+		Status: monitoringv1alpha1.CheckStatus{
+			ID:      "e71024f4-8537-4dd2-b742-ebe5a1685776",
+			PingURL: "https://hc-ping.com/e71024f4-8537-4dd2-b742-ebe5a1685776",
+		},
+	}
+	pending := &monitoringv1alpha1.Check{
+		ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"},
+	}
+	injector := newPodPingURLInjector(t, ready, pending)
+
+	// Pods without the annotation are allowed as is
+	res := injector.Handle(context.TODO(), newPodRequest(t, nil))
+	g.Expect(res.Allowed).To(BeTrue())
+	g.Expect(res.Patches).To(BeEmpty())
+
+	// Pods referencing a Check with a ping url are patched
+	res = injector.Handle(context.TODO(), newPodRequest(t, map[string]string{AnnotationCheck: "ready", AnnotationPingURLEnv: "PING"}))
+	g.Expect(res.Allowed).To(BeTrue())
+	g.Expect(res.Patches).To(HaveLen(1))
+	g.Expect(res.Patches[0].Path).To(Equal("/spec/containers/0/env"))
+	g.Expect(res.Patches[0].Value).To(ContainElement(HaveKeyWithValue("name", "PING_START")))
+
+	// Pods referencing a Check without a ping url are denied
+	res = injector.Handle(context.TODO(), newPodRequest(t, map[string]string{AnnotationCheck: "pending"}))
+	g.Expect(res.Allowed).To(BeFalse())
+
+	// Pods referencing a Check that does not exist are denied
+	res = injector.Handle(context.TODO(), newPodRequest(t, map[string]string{AnnotationCheck: "missing"}))
+	g.Expect(res.Allowed).To(BeFalse())
+}
+
+func newPodPingURLInjector(t *testing.T, objs ...runtime.Object) *PodPingURLInjector {
+	s := scheme.Scheme
+	s.AddKnownTypes(monitoringv1alpha1.GroupVersion, &monitoringv1alpha1.Check{})
+
+	decoder, err := admission.NewDecoder(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	injector := &PodPingURLInjector{Client: fake.NewFakeClientWithScheme(s, objs...)}
+	injector.InjectDecoder(decoder)
+	return injector
+}
+
+func newPodRequest(t *testing.T, annotations map[string]string) admission.Request {
+	pod := &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "backup-",
+			Annotations:  annotations,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "main", Image: "backup"}},
+		},
+	}
+
+	raw, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Namespace: "default",
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}